@@ -140,7 +140,7 @@ ${KUBEVIRT_DIR}/tools/openapispec/openapispec --dump-api-spec-path ${KUBEVIRT_DI
 (cd ${KUBEVIRT_DIR}/tools/doc-generator/ && go_build)
 (
     cd ${KUBEVIRT_DIR}/docs
-    ${KUBEVIRT_DIR}/tools/doc-generator/doc-generator
+    ${KUBEVIRT_DIR}/tools/doc-generator/doc-generator generate
     mv newmetrics.md metrics.md
 )
 
@@ -24,6 +24,8 @@ import "github.com/machadovilaca/operator-observability/pkg/operatormetrics"
 var (
 	configurationMetrics = []operatormetrics.Metric{
 		emulationEnabled,
+		deprecatedFeatureGateEnabled,
+		featureGatesConfigured,
 	}
 
 	emulationEnabled = operatormetrics.NewGauge(
@@ -32,8 +34,34 @@ var (
 			Help: "Indicates whether the Software Emulation is enabled in the configuration.",
 		},
 	)
+
+	deprecatedFeatureGateEnabled = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: "kubevirt_deprecated_feature_gate_enabled",
+			Help: "Indicates whether a deprecated feature gate is enabled in the configuration.",
+		},
+		[]string{"name"},
+	)
+
+	featureGatesConfigured = operatormetrics.NewGauge(
+		operatormetrics.MetricOpts{
+			Name: "kubevirt_feature_gates_configured",
+			Help: "The number of feature gates configured in the developer configuration.",
+		},
+	)
 )
 
 func SetEmulationEnabledMetric(isEmulationEnabled bool) {
 	emulationEnabled.Set(boolToFloat64(isEmulationEnabled))
 }
+
+func SetDeprecatedFeatureGatesEnabledMetric(featureGates []string) {
+	deprecatedFeatureGateEnabled.Reset()
+	for _, featureGate := range featureGates {
+		deprecatedFeatureGateEnabled.WithLabelValues(featureGate).Set(1)
+	}
+}
+
+func SetFeatureGatesConfiguredMetric(featureGates []string) {
+	featureGatesConfigured.Set(float64(len(featureGates)))
+}
@@ -31,4 +31,12 @@ var vmiRecordingRules = []operatorrules.RecordingRule{
 		MetricType: operatormetrics.GaugeType,
 		Expr:       intstr.FromString("kubevirt_vmi_memory_available_bytes-kubevirt_vmi_memory_usable_bytes"),
 	},
+	{
+		MetricsOpts: operatormetrics.MetricOpts{
+			Name: "kubevirt_vmi_migration_data_processed_bytes_total_sum",
+			Help: "The total Guest OS data processed and migrated to the new VMs, summed across all the migrating VMIs of the cluster.",
+		},
+		MetricType: operatormetrics.GaugeType,
+		Expr:       intstr.FromString("sum(kubevirt_vmi_migration_data_processed_bytes)"),
+	},
 }
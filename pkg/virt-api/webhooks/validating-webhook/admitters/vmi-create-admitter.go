@@ -131,9 +131,9 @@ func warnDeprecatedAPIs(spec *v1.VirtualMachineInstanceSpec, config *virtconfig.
 	var warnings []string
 	for _, fg := range config.GetConfig().DeveloperConfiguration.FeatureGates {
 		deprecatedFeature := deprecation.FeatureGateInfo(fg)
-		if deprecatedFeature != nil && deprecatedFeature.State == deprecation.Deprecated && deprecatedFeature.VmiSpecUsed != nil {
+		if deprecatedFeature != nil && deprecatedFeature.IsDeprecated() && deprecatedFeature.VmiSpecUsed != nil {
 			if used := deprecatedFeature.VmiSpecUsed(spec); used {
-				warnings = append(warnings, deprecatedFeature.Message)
+				warnings = append(warnings, deprecatedFeature.EffectiveMessage())
 			}
 		}
 	}
@@ -35,6 +35,7 @@ import (
 	"kubevirt.io/client-go/log"
 
 	"kubevirt.io/kubevirt/pkg/pointer"
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
 )
 
 const (
@@ -43,6 +44,9 @@ const (
 
 type ConfigModifiedFn func()
 
+// DeprecatedFeatureGateUsedFn is called with the deprecated feature gates found enabled in a newly loaded config
+type DeprecatedFeatureGateUsedFn func(deprecation.FeatureGate)
+
 // NewClusterConfig is a wrapper of NewClusterConfigWithCPUArch with default cpuArch.
 func NewClusterConfig(crdInformer cache.SharedIndexInformer,
 	kubeVirtInformer cache.SharedIndexInformer,
@@ -271,16 +275,23 @@ func defaultClusterConfig(cpuArch string) *v1.KubeVirtConfiguration {
 }
 
 type ClusterConfig struct {
-	crdInformer                      cache.SharedIndexInformer
-	kubeVirtInformer                 cache.SharedIndexInformer
-	namespace                        string
-	cpuArch                          string
-	lock                             *sync.Mutex
-	lastValidConfig                  *v1.KubeVirtConfiguration
-	defaultConfig                    *v1.KubeVirtConfiguration
-	lastInvalidConfigResourceVersion string
-	lastValidConfigResourceVersion   string
-	configModifiedCallback           []ConfigModifiedFn
+	crdInformer                       cache.SharedIndexInformer
+	kubeVirtInformer                  cache.SharedIndexInformer
+	namespace                         string
+	cpuArch                           string
+	lock                              *sync.Mutex
+	lastValidConfig                   *v1.KubeVirtConfiguration
+	defaultConfig                     *v1.KubeVirtConfiguration
+	lastInvalidConfigResourceVersion  string
+	lastValidConfigResourceVersion    string
+	configModifiedCallback            []ConfigModifiedFn
+	deprecatedFeatureGateUsedCallback []DeprecatedFeatureGateUsedFn
+	// loggedDeprecatedFeatureGates tracks the deprecated feature gates already logged for the current config
+	loggedDeprecatedFeatureGates map[string]struct{}
+	// loggedFeatureGateNames tracks the non-canonical feature gate names already logged for the current config
+	loggedFeatureGateNames map[string]struct{}
+	// featureGateStore provides the tracked feature gates, the built-in ones when nil
+	featureGateStore deprecation.FeatureGateStore
 }
 
 func (c *ClusterConfig) SetConfigModifiedCallback(cb ConfigModifiedFn) {
@@ -334,12 +345,22 @@ func getCPUArchSpecificDefault(cpuArch string) (string, string, []string) {
 // XXX Rework this, to happen mostly in informer callbacks.
 // This will also allow us then to react to config changes and e.g. restart some controllers
 func (c *ClusterConfig) GetConfig() (config *v1.KubeVirtConfiguration) {
+	config, notify := c.loadConfig()
+	// the callbacks run once the lock is released, so that they can use the config
+	notify()
+	return config
+}
+
+// loadConfig does the work of GetConfig with the config lock held, returning along with the config a func
+// invoking the deprecated feature gate callbacks when a new version of the config was loaded
+func (c *ClusterConfig) loadConfig() (config *v1.KubeVirtConfiguration, notify func()) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	notify = func() {}
 	kv := c.GetConfigFromKubeVirtCR()
 	if kv == nil {
-		return c.lastValidConfig
+		return c.lastValidConfig, notify
 	}
 
 	resourceVersion := kv.ResourceVersion
@@ -348,7 +369,7 @@ func (c *ClusterConfig) GetConfig() (config *v1.KubeVirtConfiguration) {
 	// and ignore configuration in kubevirt
 	if c.lastValidConfigResourceVersion == resourceVersion ||
 		c.lastInvalidConfigResourceVersion == resourceVersion {
-		return c.lastValidConfig
+		return c.lastValidConfig, notify
 	}
 
 	config = defaultClusterConfig(c.cpuArch)
@@ -356,13 +377,15 @@ func (c *ClusterConfig) GetConfig() (config *v1.KubeVirtConfiguration) {
 	if err != nil {
 		c.lastInvalidConfigResourceVersion = resourceVersion
 		log.DefaultLogger().Reason(err).Errorf("Invalid cluster config using KubeVirt resource version '%s', falling back to last good resource version '%s'", resourceVersion, c.lastValidConfigResourceVersion)
-		return c.lastValidConfig
+		return c.lastValidConfig, notify
 	}
 
 	log.DefaultLogger().Infof("Updating cluster config from KubeVirt to resource version '%s'", resourceVersion)
 	c.lastValidConfigResourceVersion = resourceVersion
 	c.lastValidConfig = config
-	return c.lastValidConfig
+	c.loggedDeprecatedFeatureGates = nil
+	c.loggedFeatureGateNames = nil
+	return c.lastValidConfig, c.deprecatedFeatureGatesNotifier()
 }
 
 func (c *ClusterConfig) GetConfigFromKubeVirtCR() *v1.KubeVirt {
@@ -1,7 +1,9 @@
 package virtconfig_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"os"
 	"strings"
 	"sync"
 
@@ -11,12 +13,15 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/cache"
 
 	v1 "kubevirt.io/api/core/v1"
+	"kubevirt.io/client-go/log"
 
 	cmdv1 "kubevirt.io/kubevirt/pkg/handler-launcher-com/cmd/v1"
 	"kubevirt.io/kubevirt/pkg/testutils"
 	virtconfig "kubevirt.io/kubevirt/pkg/virt-config"
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
 
 	"kubevirt.io/kubevirt/pkg/pointer"
 )
@@ -783,4 +788,229 @@ var _ = Describe("test configuration", func() {
 			Expect(clusterConfig.SRIOVLiveMigrationEnabled()).To(BeTrue())
 		})
 	})
+
+	DescribeTable("FeatureGateStatus should return the enablement along with the tracked record", func(featureGate string, expectedEnabled bool, expectedState string) {
+		clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+			DeveloperConfiguration: &v1.DeveloperConfiguration{
+				FeatureGates: []string{deprecation.PasstGate, virtconfig.CPUManager},
+			},
+		})
+
+		enabled, info := clusterConfig.FeatureGateStatus(featureGate)
+		Expect(enabled).To(Equal(expectedEnabled))
+		if expectedState == "" {
+			Expect(info).To(BeNil())
+		} else {
+			Expect(info).ToNot(BeNil())
+			Expect(info.State).To(BeEquivalentTo(expectedState))
+		}
+	},
+		Entry("GA gate", deprecation.LiveMigrationGate, true, deprecation.GA),
+		Entry("enabled deprecated gate", deprecation.PasstGate, true, deprecation.Deprecated),
+		Entry("disabled deprecated gate", deprecation.MacvtapGate, false, deprecation.Deprecated),
+		Entry("enabled untracked gate", virtconfig.CPUManager, true, ""),
+		Entry("disabled untracked gate", virtconfig.ExpandDisksGate, false, ""),
+	)
+
+	It("EnabledDeprecatedFeatureGates should only list the enabled deprecated feature gates", func() {
+		clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+			DeveloperConfiguration: &v1.DeveloperConfiguration{
+				FeatureGates: []string{deprecation.LiveMigrationGate, deprecation.PasstGate, virtconfig.CPUManager},
+			},
+		})
+
+		var names []string
+		for _, fg := range clusterConfig.EnabledDeprecatedFeatureGates() {
+			names = append(names, fg.Name)
+		}
+		Expect(names).To(ConsistOf(deprecation.PasstGate))
+	})
+
+	DescribeTable("UnknownFeatureGates should only report the unrecognized feature gates", func(featureGates, expected []string) {
+		clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+			DeveloperConfiguration: &v1.DeveloperConfiguration{
+				FeatureGates: featureGates,
+			},
+		})
+		Expect(clusterConfig.UnknownFeatureGates()).To(Equal(expected))
+	},
+		Entry("GA gate", []string{deprecation.LiveMigrationGate}, nil),
+		Entry("deprecated gate", []string{deprecation.PasstGate}, nil),
+		Entry("garbage name", []string{deprecation.LiveMigrationGate, "NotAFeatureGate"}, []string{"NotAFeatureGate"}),
+	)
+
+	DescribeTable("ActiveFeatureGates should only return the configured feature gates changing the behavior", func(featureGates, expected []string) {
+		clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+			DeveloperConfiguration: &v1.DeveloperConfiguration{
+				FeatureGates: featureGates,
+			},
+		})
+		Expect(clusterConfig.ActiveFeatureGates()).To(Equal(expected))
+	},
+		Entry("GA, deprecated, untracked and unknown gates",
+			[]string{deprecation.LiveMigrationGate, deprecation.PasstGate, virtconfig.CPUManager, "NotAFeatureGate"},
+			[]string{deprecation.PasstGate, virtconfig.CPUManager}),
+		Entry("gates set more than once", []string{virtconfig.CPUManager, "cpumanager"}, []string{virtconfig.CPUManager}),
+		Entry("only GA and unknown gates", []string{deprecation.LiveMigrationGate, "NotAFeatureGate"}, nil),
+		Entry("empty config", nil, nil),
+	)
+
+	DescribeTable("HasEnabledDeprecatedFeatureGate", func(featureGates []string, expected bool) {
+		clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+			DeveloperConfiguration: &v1.DeveloperConfiguration{
+				FeatureGates: featureGates,
+			},
+		})
+		Expect(clusterConfig.HasEnabledDeprecatedFeatureGate()).To(Equal(expected))
+	},
+		Entry("should be true with the Passt gate enabled", []string{virtconfig.CPUManager, deprecation.PasstGate}, true),
+		Entry("should be false with only non deprecated gates enabled", []string{deprecation.LiveMigrationGate, virtconfig.CPUManager}, false),
+		Entry("should be false for an empty config", nil, false),
+	)
+
+	Context("with an injected feature gate store", func() {
+		const storeGate = "StoreGate"
+
+		newClusterConfig := func(featureGates ...string) *virtconfig.ClusterConfig {
+			clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+				DeveloperConfiguration: &v1.DeveloperConfiguration{FeatureGates: featureGates},
+			})
+			store, err := deprecation.NewFeatureGateStore(
+				deprecation.FeatureGate{Name: storeGate, State: deprecation.Beta},
+				deprecation.FeatureGate{Name: deprecation.LiveMigrationGate, State: deprecation.Discontinued},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			clusterConfig.SetFeatureGateStore(store)
+			return clusterConfig
+		}
+
+		DescribeTable("FeatureGateStatus should only consult the store", func(featureGate string, configured []string, expectedEnabled bool, expectedState string) {
+			enabled, info := newClusterConfig(configured...).FeatureGateStatus(featureGate)
+			Expect(enabled).To(Equal(expectedEnabled))
+			if expectedState == "" {
+				Expect(info).To(BeNil())
+			} else {
+				Expect(info).ToNot(BeNil())
+				Expect(info.State).To(BeEquivalentTo(expectedState))
+			}
+		},
+			Entry("unset gate of the store", storeGate, nil, false, deprecation.Beta),
+			Entry("set gate of the store", storeGate, []string{storeGate}, true, deprecation.Beta),
+			Entry("built-in GA gate discontinued by the store", deprecation.LiveMigrationGate, []string{deprecation.LiveMigrationGate}, false, deprecation.Discontinued),
+			Entry("built-in deprecated gate missing from the store", deprecation.PasstGate, []string{deprecation.PasstGate}, true, ""),
+		)
+
+		It("FeatureGateTransitionWarnings should report the gates whose state regressed since the upgrade", func() {
+			clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{})
+			store, err := deprecation.NewFeatureGateStore(
+				deprecation.FeatureGate{Name: deprecation.PasstGate, State: deprecation.Discontinued},
+				deprecation.FeatureGate{Name: deprecation.MacvtapGate, State: deprecation.Deprecated},
+				deprecation.FeatureGate{Name: storeGate, State: deprecation.GA},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			clusterConfig.SetFeatureGateStore(store)
+
+			Expect(clusterConfig.FeatureGateTransitionWarnings(map[string]deprecation.State{
+				"passt":                       deprecation.Deprecated,
+				deprecation.MacvtapGate:       deprecation.Deprecated,
+				storeGate:                     deprecation.Beta,
+				deprecation.LiveMigrationGate: deprecation.GA,
+			})).To(Equal([]string{
+				`feature gate Passt went from the "Deprecated" to the "Discontinued" state, the functionality it enabled is no longer available`,
+			}))
+		})
+
+		It("ValidateFeatureGates should validate against the store", func() {
+			errs := newClusterConfig(deprecation.LiveMigrationGate).ValidateFeatureGates()
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring("feature gate LiveMigration is discontinued")))
+		})
+	})
+
+	Context("feature gate name warnings", func() {
+		var logs *bytes.Buffer
+
+		BeforeEach(func() {
+			logs = &bytes.Buffer{}
+			log.Log.SetIOWriter(logs)
+			DeferCleanup(log.Log.SetIOWriter, os.Stderr)
+		})
+
+		It("should be logged once per config for a feature gate configured with a different casing", func() {
+			clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+				DeveloperConfiguration: &v1.DeveloperConfiguration{FeatureGates: []string{"expanddisks"}},
+			})
+			Expect(clusterConfig.ExpandDisksEnabled()).To(BeTrue())
+			Expect(clusterConfig.ExpandDisksEnabled()).To(BeTrue())
+			Expect(strings.Count(logs.String(), "is configured with a different casing")).To(Equal(1))
+		})
+
+		It("should be logged once per config for a feature gate configured by its alias", func() {
+			clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+				DeveloperConfiguration: &v1.DeveloperConfiguration{FeatureGates: []string{"ExpandVolumes"}},
+			})
+			store, err := deprecation.NewFeatureGateStore(
+				deprecation.FeatureGate{Name: virtconfig.ExpandDisksGate, State: deprecation.Beta, Aliases: []string{"ExpandVolumes"}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			clusterConfig.SetFeatureGateStore(store)
+
+			Expect(clusterConfig.ExpandDisksEnabled()).To(BeTrue())
+			Expect(clusterConfig.ExpandDisksEnabled()).To(BeTrue())
+			Expect(strings.Count(logs.String(), `feature gate \"ExpandVolumes\" was renamed`)).To(Equal(1))
+		})
+	})
+
+	Context("OnDeprecatedFeatureGateUsed", func() {
+		var (
+			clusterConfig    *virtconfig.ClusterConfig
+			kubeVirtInformer cache.SharedIndexInformer
+			kv               *v1.KubeVirt
+			usedGates        []string
+		)
+
+		BeforeEach(func() {
+			kv = &v1.KubeVirt{
+				Spec: v1.KubeVirtSpec{
+					Configuration: v1.KubeVirtConfiguration{
+						DeveloperConfiguration: &v1.DeveloperConfiguration{
+							FeatureGates: []string{deprecation.LiveMigrationGate, deprecation.PasstGate},
+						},
+					},
+				},
+			}
+			clusterConfig, _, kubeVirtInformer = testutils.NewFakeClusterConfigUsingKVConfig(&kv.Spec.Configuration)
+
+			usedGates = nil
+			clusterConfig.OnDeprecatedFeatureGateUsed(func(fg deprecation.FeatureGate) {
+				usedGates = append(usedGates, fg.Name)
+			})
+		})
+
+		It("should be called once per config version with the enabled deprecated feature gates", func() {
+			clusterConfig.GetConfig()
+			Expect(usedGates).To(Equal([]string{deprecation.PasstGate}))
+
+			clusterConfig.GetConfig()
+			clusterConfig.GetConfig()
+			Expect(usedGates).To(Equal([]string{deprecation.PasstGate}))
+		})
+
+		It("should be called again when the config is updated", func() {
+			clusterConfig.GetConfig()
+			testutils.UpdateFakeKubeVirtClusterConfig(kubeVirtInformer, kv)
+			clusterConfig.GetConfig()
+			clusterConfig.GetConfig()
+			Expect(usedGates).To(Equal([]string{deprecation.PasstGate, deprecation.PasstGate}))
+		})
+
+		It("should be able to use the config", func() {
+			var enabled bool
+			clusterConfig.OnDeprecatedFeatureGateUsed(func(fg deprecation.FeatureGate) {
+				enabled = clusterConfig.HasEnabledDeprecatedFeatureGate()
+			})
+			clusterConfig.GetConfig()
+			Expect(enabled).To(BeTrue())
+		})
+	})
 })
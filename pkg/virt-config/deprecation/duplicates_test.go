@@ -0,0 +1,45 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package deprecation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("duplicate feature gates", func() {
+	It("should not exist among the built-in feature gates", func() {
+		Expect(checkDuplicateFeatureGates(copyFeatureGates())).To(Succeed())
+	})
+
+	DescribeTable("should be detected", func(fgs []FeatureGate, expected string) {
+		Expect(checkDuplicateFeatureGates(fgs)).To(MatchError(expected))
+	},
+		Entry("with the same name",
+			[]FeatureGate{{Name: "Foo", State: GA}, {Name: "Foo", State: Deprecated}},
+			"feature gate name Foo is used by both Foo and Foo"),
+		Entry("with a different casing",
+			[]FeatureGate{{Name: "Foo", State: GA}, {Name: "foo", State: Deprecated}},
+			"feature gate name foo is used by both Foo and foo"),
+		Entry("with an alias of another feature gate",
+			[]FeatureGate{{Name: "Foo", State: GA, Aliases: []string{"Bar"}}, {Name: "Bar", State: Deprecated}},
+			"feature gate name Bar is used by both Foo and Bar"),
+	)
+})
@@ -21,6 +21,10 @@ package deprecation
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
 
 	v1 "kubevirt.io/api/core/v1"
 )
@@ -28,13 +32,24 @@ import (
 type State string
 
 const (
+	// Alpha and Beta feature gates are only enabled when explicitly set.
+	Alpha = "Alpha"
+	Beta  = "Beta"
 	// By default, GAed feature gates are considered enabled and no-op.
 	GA = "General Availability"
 	// The feature is going to be discontinued next release
-	Deprecated     = "Deprecated"
+	Deprecated = "Deprecated"
+	// The feature still works like a Deprecated one, but its removal is imminent
+	PendingRemoval = "PendingRemoval"
 	Discontinued   = "Discontinued"
-	WarningPattern = "feature gate %s is deprecated (feature state is %q), therefore it can be safely removed and is redundant. " +
-		"For more info, please look at: https://github.com/kubevirt/kubevirt/blob/main/docs/deprecation.md"
+	WarningPattern = warningStatePattern + warningMoreInfo
+
+	warningStatePattern          = "feature gate %s is deprecated (feature state is %q), therefore it can be safely removed and is redundant. "
+	pendingRemovalWarningPattern = "feature gate %s is about to be removed (feature state is %q), it stops working in an upcoming release and must not be used anymore. "
+	warningMoreInfo              = moreInfoPrefix + deprecationDocURL
+
+	moreInfoPrefix    = "For more info, please look at: "
+	deprecationDocURL = "https://github.com/kubevirt/kubevirt/blob/main/docs/deprecation.md"
 )
 
 const (
@@ -52,33 +67,243 @@ type FeatureGate struct {
 	State       State
 	VmiSpecUsed func(spec *v1.VirtualMachineInstanceSpec) bool
 	Message     string
+	// DeprecatedInVersion and RemovedInVersion are the KubeVirt versions, e.g. "v1.2", the feature gate
+	// was deprecated in and is scheduled to be removed in
+	DeprecatedInVersion string
+	RemovedInVersion    string
+	// Aliases are former names of a renamed feature gate which still resolve to it
+	Aliases []string
+	// Requires are the names of the feature gates which must be enabled for this one to work
+	Requires []string
+	// DefaultEnabled gates are enabled unless they are listed in the disabled feature gates
+	DefaultEnabled bool
+	// DocURL links to the documentation specific to the feature gate, e.g. its migration guide,
+	// the generic deprecation documentation is linked when empty
+	DocURL string
 }
 
-var featureGates = [...]FeatureGate{
-	{Name: LiveMigrationGate, State: GA},
-	{Name: SRIOVLiveMigrationGate, State: GA},
-	{Name: NonRoot, State: GA},
-	{Name: PSA, State: GA},
-	{Name: CPUNodeDiscoveryGate, State: GA},
-	{Name: PasstGate, State: Deprecated, Message: PasstDeprecationMessage, VmiSpecUsed: passtApiUsed},
-	{Name: MacvtapGate, State: Deprecated, Message: MacvtapDeprecationMessage, VmiSpecUsed: macvtapApiUsed},
+// DocumentationURL returns the DocURL of the feature gate, or the generic deprecation documentation if it has none
+func (fg FeatureGate) DocumentationURL() string {
+	if fg.DocURL != "" {
+		return fg.DocURL
+	}
+	return deprecationDocURL
 }
 
+// Matches reports whether the name refers to the feature gate, either by its name or one of its aliases,
+// ignoring the casing
+func (fg FeatureGate) Matches(name string) bool {
+	if strings.EqualFold(name, fg.Name) {
+		return true
+	}
+	for _, alias := range fg.Aliases {
+		if strings.EqualFold(name, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	featureGatesLock sync.RWMutex
+	featureGates     = []FeatureGate{
+		{Name: LiveMigrationGate, State: GA},
+		{Name: SRIOVLiveMigrationGate, State: GA},
+		{Name: NonRoot, State: GA},
+		{Name: PSA, State: GA},
+		{Name: CPUNodeDiscoveryGate, State: GA},
+		{Name: PasstGate, State: Deprecated, Message: PasstDeprecationMessage, VmiSpecUsed: passtApiUsed},
+		{Name: MacvtapGate, State: Deprecated, Message: MacvtapDeprecationMessage, VmiSpecUsed: macvtapApiUsed},
+	}
+)
+
 func init() {
-	for i, fg := range featureGates {
-		if fg.Message == "" {
+	if err := checkDuplicateFeatureGates(featureGates); err != nil {
+		panic(err)
+	}
+}
 
-			featureGates[i].Message = fmt.Sprintf(WarningPattern, fg.Name, fg.State)
+// checkDuplicateFeatureGates fails if any name or alias refers to more than one of the feature gates,
+// as the lookups would silently resolve to whichever comes first
+func checkDuplicateFeatureGates(fgs []FeatureGate) error {
+	owners := map[string]string{}
+	for _, fg := range fgs {
+		for _, name := range append([]string{fg.Name}, fg.Aliases...) {
+			key := strings.ToLower(name)
+			if owner, exists := owners[key]; exists {
+				return fmt.Errorf("feature gate name %s is used by both %s and %s", name, owner, fg.Name)
+			}
+			owners[key] = fg.Name
 		}
 	}
+	return nil
 }
 
+// RegisterFeatureGate adds a feature gate to the tracked ones, e.g. from downstream builds at startup.
+// It fails if a feature gate with the same name is already tracked.
+func RegisterFeatureGate(fg FeatureGate) error {
+	featureGatesLock.Lock()
+	defer featureGatesLock.Unlock()
+
+	for _, registered := range featureGates {
+		for _, name := range append([]string{fg.Name}, fg.Aliases...) {
+			if registered.Matches(name) {
+				return fmt.Errorf("feature gate %s is already registered", registered.Name)
+			}
+		}
+	}
+
+	featureGates = append(featureGates, fg)
+	return nil
+}
+
+// EffectiveMessage returns the message to warn about the feature gate with. An empty Message defaults to
+// a generic one for feature gates past Beta, while a custom one may refer to the fields of the feature gate
+// as a text/template, e.g. "{{.Name}} is going to be removed in {{.RemovedInVersion}}, see {{.DocumentationURL}}".
+// Messages which are not valid templates are returned as they are.
+func (fg FeatureGate) EffectiveMessage() string {
+	if fg.Message == "" {
+		if fg.State == Alpha || fg.State == Beta {
+			return ""
+		}
+		return defaultMessage(fg)
+	}
+	if !strings.Contains(fg.Message, "{{") {
+		return fg.Message
+	}
+
+	tmpl, err := template.New(fg.Name).Option("missingkey=error").Parse(fg.Message)
+	if err != nil {
+		return fg.Message
+	}
+	var message strings.Builder
+	if err := tmpl.Execute(&message, fg); err != nil {
+		return fg.Message
+	}
+	return message.String()
+}
+
+func defaultMessage(fg FeatureGate) string {
+	pattern := warningStatePattern
+	if fg.State == PendingRemoval {
+		pattern = pendingRemovalWarningPattern
+	}
+	return fmt.Sprintf(pattern, fg.Name, fg.State) + versionsNote(fg) + moreInfoPrefix + fg.DocumentationURL()
+}
+
+// versionsNote describes when the feature gate was deprecated and is going to be removed, if known
+func versionsNote(fg FeatureGate) string {
+	switch {
+	case fg.DeprecatedInVersion != "" && fg.RemovedInVersion != "":
+		return fmt.Sprintf("It was deprecated in %s, scheduled for removal in %s. ", fg.DeprecatedInVersion, fg.RemovedInVersion)
+	case fg.DeprecatedInVersion != "":
+		return fmt.Sprintf("It was deprecated in %s. ", fg.DeprecatedInVersion)
+	case fg.RemovedInVersion != "":
+		return fmt.Sprintf("It is scheduled for removal in %s. ", fg.RemovedInVersion)
+	}
+	return ""
+}
+
+// FeatureGateInfo returns the tracked feature gate matching the name or one of its aliases case-insensitively,
+// the returned record holds the canonical name of the feature gate
 func FeatureGateInfo(featureGate string) *FeatureGate {
+	featureGatesLock.RLock()
+	defer featureGatesLock.RUnlock()
+
 	for _, deprecatedFeature := range featureGates {
-		if featureGate == deprecatedFeature.Name {
+		if deprecatedFeature.Matches(featureGate) {
 			deprecatedFeature := deprecatedFeature
 			return &deprecatedFeature
 		}
 	}
 	return nil
 }
+
+// IsDeprecated reports whether the feature gate still works but is going to be removed, i.e. it is
+// in the Deprecated or PendingRemoval state
+func (fg FeatureGate) IsDeprecated() bool {
+	return fg.State == Deprecated || fg.State == PendingRemoval
+}
+
+// IsDeprecated reports whether the feature gate is tracked in the Deprecated or PendingRemoval state
+func IsDeprecated(featureGate string) bool {
+	info := FeatureGateInfo(featureGate)
+	return info != nil && info.IsDeprecated()
+}
+
+// IsGA reports whether the feature gate is tracked in the GA state
+func IsGA(featureGate string) bool {
+	return hasState(featureGate, GA)
+}
+
+// IsDiscontinued reports whether the feature gate is tracked in the Discontinued state
+func IsDiscontinued(featureGate string) bool {
+	return hasState(featureGate, Discontinued)
+}
+
+func hasState(featureGate string, state State) bool {
+	info := FeatureGateInfo(featureGate)
+	return info != nil && info.State == state
+}
+
+// AllFeatureGates returns a copy of all the tracked feature gates sorted by name
+func AllFeatureGates() []FeatureGate {
+	all := copyFeatureGates()
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// AllFeatureGatesByState returns a copy of all the tracked feature gates sorted by
+// their lifecycle state, from Alpha to Discontinued, and then by name
+func AllFeatureGatesByState() []FeatureGate {
+	all := copyFeatureGates()
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].State != all[j].State {
+			return stateOrder(all[i].State) < stateOrder(all[j].State)
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// DeprecatedOnlyFeatureGates returns a copy of the tracked feature gates which still work but are
+// going to be removed, i.e. in the Deprecated or PendingRemoval state, sorted by name
+func DeprecatedOnlyFeatureGates() []FeatureGate {
+	var deprecated []FeatureGate
+	for _, fg := range AllFeatureGates() {
+		if fg.IsDeprecated() {
+			deprecated = append(deprecated, fg)
+		}
+	}
+	return deprecated
+}
+
+var lifecycle = []State{Alpha, Beta, GA, Deprecated, PendingRemoval, Discontinued}
+
+// stateOrder returns the position of the state in the feature gates lifecycle, unknown states come last
+func stateOrder(state State) int {
+	for i, s := range lifecycle {
+		if s == state {
+			return i
+		}
+	}
+	return len(lifecycle)
+}
+
+// IsRegression reports whether a feature gate going from the previous state to the current one got
+// closer to its removal, e.g. from Deprecated to Discontinued. Progressing up to GA is not a regression
+func IsRegression(previous, current State) bool {
+	order := stateOrder(current)
+	return order < len(lifecycle) && order > stateOrder(GA) && order > stateOrder(previous)
+}
+
+func copyFeatureGates() []FeatureGate {
+	featureGatesLock.RLock()
+	defer featureGatesLock.RUnlock()
+
+	all := make([]FeatureGate, len(featureGates))
+	copy(all, featureGates)
+	return all
+}
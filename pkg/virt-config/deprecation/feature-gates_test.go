@@ -0,0 +1,203 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package deprecation_test
+
+import (
+	"sort"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
+)
+
+var _ = Describe("deprecated feature gates", func() {
+	DescribeTable("FeatureGateInfo should match names case-insensitively and return the canonical name", func(name, canonicalName string, state string) {
+		info := deprecation.FeatureGateInfo(name)
+		Expect(info).ToNot(BeNil())
+		Expect(info.Name).To(Equal(canonicalName))
+		Expect(info.State).To(BeEquivalentTo(state))
+	},
+		Entry("GA gate", "livemigration", deprecation.LiveMigrationGate, deprecation.GA),
+		Entry("Deprecated gate", "MacVTap", deprecation.MacvtapGate, deprecation.Deprecated),
+	)
+
+	DescribeTable("state predicates", func(name string, isDeprecated, isGA, isDiscontinued bool) {
+		Expect(deprecation.IsDeprecated(name)).To(Equal(isDeprecated))
+		Expect(deprecation.IsGA(name)).To(Equal(isGA))
+		Expect(deprecation.IsDiscontinued(name)).To(Equal(isDiscontinued))
+	},
+		Entry("GA gate", deprecation.LiveMigrationGate, false, true, false),
+		Entry("Deprecated gate", deprecation.PasstGate, true, false, false),
+		Entry("Deprecated gate with a different casing", "macvtap", true, false, false),
+		Entry("unknown gate", "UnknownGate", false, false, false),
+	)
+
+	Context("AllFeatureGates", func() {
+		It("should list all the tracked feature gates", func() {
+			var names []string
+			for _, fg := range deprecation.AllFeatureGates() {
+				names = append(names, fg.Name)
+			}
+			Expect(names).To(ContainElements(deprecation.LiveMigrationGate, deprecation.PasstGate, deprecation.MacvtapGate))
+		})
+
+		names := func(featureGates []deprecation.FeatureGate) []string {
+			var names []string
+			for _, fg := range featureGates {
+				names = append(names, fg.Name)
+			}
+			return names
+		}
+
+		It("should list the feature gates sorted by name", func() {
+			sorted := names(deprecation.AllFeatureGates())
+			Expect(sort.StringsAreSorted(sorted)).To(BeTrue())
+			Expect(names(deprecation.AllFeatureGates())).To(Equal(sorted))
+		})
+
+		It("should list the feature gates sorted by state and then by name", func() {
+			sorted := names(deprecation.AllFeatureGatesByState())
+			Expect(names(deprecation.AllFeatureGatesByState())).To(Equal(sorted))
+
+			indexOf := func(name string) int {
+				for i, n := range sorted {
+					if n == name {
+						return i
+					}
+				}
+				return -1
+			}
+			Expect(indexOf(deprecation.CPUNodeDiscoveryGate)).To(BeNumerically("<", indexOf(deprecation.LiveMigrationGate)))
+			Expect(indexOf(deprecation.LiveMigrationGate)).To(BeNumerically("<", indexOf(deprecation.MacvtapGate)))
+			Expect(indexOf(deprecation.MacvtapGate)).To(BeNumerically("<", indexOf(deprecation.PasstGate)))
+		})
+
+		It("DeprecatedOnlyFeatureGates should only list the built-in Deprecated gates", func() {
+			deprecated := deprecation.DeprecatedOnlyFeatureGates()
+			for _, fg := range deprecated {
+				Expect(fg.IsDeprecated()).To(BeTrue(), fg.Name)
+			}
+			Expect(names(deprecated)).To(ContainElements(deprecation.PasstGate, deprecation.MacvtapGate))
+			Expect(names(deprecated)).ToNot(ContainElements(deprecation.LiveMigrationGate, deprecation.CPUNodeDiscoveryGate))
+		})
+
+		It("should not allow mutating the tracked feature gates", func() {
+			all := deprecation.AllFeatureGates()
+			for i := range all {
+				all[i].State = deprecation.Discontinued
+			}
+
+			Expect(deprecation.FeatureGateInfo(deprecation.LiveMigrationGate).State).To(BeEquivalentTo(deprecation.GA))
+		})
+	})
+
+	Context("RegisterFeatureGate", func() {
+		It("should track the registered feature gate", func() {
+			Expect(deprecation.RegisterFeatureGate(deprecation.FeatureGate{Name: "DownstreamGate", State: deprecation.Deprecated})).To(Succeed())
+
+			info := deprecation.FeatureGateInfo("DownstreamGate")
+			Expect(info).ToNot(BeNil())
+			Expect(info.State).To(BeEquivalentTo(deprecation.Deprecated))
+			Expect(info.EffectiveMessage()).To(ContainSubstring("feature gate DownstreamGate is deprecated"))
+		})
+
+		It("should report a feature gate pending removal as deprecated", func() {
+			Expect(deprecation.RegisterFeatureGate(deprecation.FeatureGate{Name: "PendingRemovalGate", State: deprecation.PendingRemoval})).To(Succeed())
+			Expect(deprecation.IsDeprecated("PendingRemovalGate")).To(BeTrue())
+			Expect(deprecation.IsDiscontinued("PendingRemovalGate")).To(BeFalse())
+		})
+
+		It("should report the state of the registered feature gate", func() {
+			Expect(deprecation.RegisterFeatureGate(deprecation.FeatureGate{Name: "DiscontinuedGate", State: deprecation.Discontinued})).To(Succeed())
+
+			Expect(deprecation.IsDiscontinued("DiscontinuedGate")).To(BeTrue())
+			Expect(deprecation.IsDeprecated("DiscontinuedGate")).To(BeFalse())
+			Expect(deprecation.IsGA("DiscontinuedGate")).To(BeFalse())
+		})
+
+		It("should resolve aliases to the canonical feature gate", func() {
+			Expect(deprecation.RegisterFeatureGate(deprecation.FeatureGate{Name: "RenamedGate", State: deprecation.GA, Aliases: []string{"FormerGate"}})).To(Succeed())
+
+			info := deprecation.FeatureGateInfo("formergate")
+			Expect(info).ToNot(BeNil())
+			Expect(info.Name).To(Equal("RenamedGate"))
+			Expect(info.State).To(BeEquivalentTo(deprecation.GA))
+		})
+
+		It("should fail to register a feature gate using a tracked alias", func() {
+			err := deprecation.RegisterFeatureGate(deprecation.FeatureGate{Name: "AnotherGate", State: deprecation.GA, Aliases: []string{deprecation.MacvtapGate}})
+			Expect(err).To(MatchError(ContainSubstring("feature gate Macvtap is already registered")))
+		})
+
+		It("should fail to register an already tracked feature gate", func() {
+			err := deprecation.RegisterFeatureGate(deprecation.FeatureGate{Name: "passt", State: deprecation.GA})
+			Expect(err).To(MatchError(ContainSubstring("feature gate Passt is already registered")))
+			Expect(deprecation.FeatureGateInfo(deprecation.PasstGate).State).To(BeEquivalentTo(deprecation.Deprecated))
+		})
+	})
+
+	Context("FeatureGateStore", func() {
+		It("the default store should provide the tracked feature gates", func() {
+			store := deprecation.DefaultFeatureGateStore()
+			Expect(store.Lookup("passt")).ToNot(BeNil())
+			Expect(store.Lookup("passt").Name).To(Equal(deprecation.PasstGate))
+			Expect(store.All()).To(HaveLen(len(deprecation.AllFeatureGates())))
+		})
+
+		It("a new store should only provide the given feature gates", func() {
+			store, err := deprecation.NewFeatureGateStore(
+				deprecation.FeatureGate{Name: "Foo", State: deprecation.Beta, Aliases: []string{"OldFoo"}},
+				deprecation.FeatureGate{Name: "Bar", State: deprecation.GA},
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(store.Lookup("oldfoo")).ToNot(BeNil())
+			Expect(store.Lookup("oldfoo").Name).To(Equal("Foo"))
+			Expect(store.Lookup(deprecation.PasstGate)).To(BeNil())
+
+			all := store.All()
+			Expect(all).To(HaveLen(2))
+			Expect(all[0].Name).To(Equal("Bar"))
+			Expect(all[1].Name).To(Equal("Foo"))
+
+			all[0].State = deprecation.Discontinued
+			Expect(store.Lookup("Bar").State).To(BeEquivalentTo(deprecation.GA))
+		})
+
+		It("a new store should fail on duplicate feature gates", func() {
+			_, err := deprecation.NewFeatureGateStore(deprecation.FeatureGate{Name: "Foo"}, deprecation.FeatureGate{Name: "FOO"})
+			Expect(err).To(MatchError("feature gate name FOO is used by both Foo and FOO"))
+		})
+	})
+
+	DescribeTable("IsRegression", func(previous, current deprecation.State, expected bool) {
+		Expect(deprecation.IsRegression(previous, current)).To(Equal(expected))
+	},
+		Entry("Deprecated to Discontinued", deprecation.State(deprecation.Deprecated), deprecation.State(deprecation.Discontinued), true),
+		Entry("GA to Deprecated", deprecation.State(deprecation.GA), deprecation.State(deprecation.Deprecated), true),
+		Entry("Deprecated to PendingRemoval", deprecation.State(deprecation.Deprecated), deprecation.State(deprecation.PendingRemoval), true),
+		Entry("Beta to GA", deprecation.State(deprecation.Beta), deprecation.State(deprecation.GA), false),
+		Entry("Alpha to Beta", deprecation.State(deprecation.Alpha), deprecation.State(deprecation.Beta), false),
+		Entry("unchanged Deprecated", deprecation.State(deprecation.Deprecated), deprecation.State(deprecation.Deprecated), false),
+		Entry("Discontinued to Deprecated", deprecation.State(deprecation.Discontinued), deprecation.State(deprecation.Deprecated), false),
+		Entry("to an unknown state", deprecation.State(deprecation.Deprecated), deprecation.State("Retired"), false),
+	)
+})
@@ -0,0 +1,94 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package deprecation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("feature gate message", func() {
+	DescribeTable("default message should mention the deprecation and removal versions when known", func(deprecatedIn, removedIn, expectedNote string) {
+		message := FeatureGate{
+			Name:                "Foo",
+			State:               Deprecated,
+			DeprecatedInVersion: deprecatedIn,
+			RemovedInVersion:    removedIn,
+		}.EffectiveMessage()
+		Expect(message).To(Equal(`feature gate Foo is deprecated (feature state is "Deprecated"), therefore it can be safely removed and is redundant. ` +
+			expectedNote + warningMoreInfo))
+	},
+		Entry("without versions", "", "", ""),
+		Entry("with both versions", "v1.2", "v1.4", "It was deprecated in v1.2, scheduled for removal in v1.4. "),
+		Entry("with the deprecation version only", "v1.2", "", "It was deprecated in v1.2. "),
+		Entry("with the removal version only", "", "v1.4", "It is scheduled for removal in v1.4. "),
+	)
+
+	DescribeTable("should be empty without a custom message only before GA", func(state State, expectEmpty bool) {
+		Expect(FeatureGate{Name: "Foo", State: state}.EffectiveMessage() == "").To(Equal(expectEmpty))
+	},
+		Entry("Alpha", State(Alpha), true),
+		Entry("Beta", State(Beta), true),
+		Entry("GA", State(GA), false),
+		Entry("Deprecated", State(Deprecated), false),
+		Entry("PendingRemoval", State(PendingRemoval), false),
+		Entry("Discontinued", State(Discontinued), false),
+	)
+
+	It("default message should link the documentation of the feature gate when it has one", func() {
+		message := FeatureGate{Name: "Foo", State: Deprecated, DocURL: "https://kubevirt.io/user-guide/foo-migration/"}.EffectiveMessage()
+		Expect(message).To(Equal(`feature gate Foo is deprecated (feature state is "Deprecated"), therefore it can be safely removed and is redundant. ` +
+			"For more info, please look at: https://kubevirt.io/user-guide/foo-migration/"))
+		Expect(message).ToNot(ContainSubstring("deprecation.md"))
+	})
+
+	It("default message of a feature gate pending removal should signal the imminent removal", func() {
+		message := FeatureGate{Name: "Foo", State: PendingRemoval, RemovedInVersion: "v1.4"}.EffectiveMessage()
+		Expect(message).To(Equal(`feature gate Foo is about to be removed (feature state is "PendingRemoval"), it stops working in an upcoming release and must not be used anymore. ` +
+			"It is scheduled for removal in v1.4. " + warningMoreInfo))
+	})
+
+	DescribeTable("custom message", func(message, expected string) {
+		fg := FeatureGate{Name: "Foo", State: Deprecated, Message: message, DeprecatedInVersion: "v1.2", RemovedInVersion: "v1.4"}
+		Expect(fg.EffectiveMessage()).To(Equal(expected))
+	},
+		Entry("should be returned as is", "Foo is going away.", "Foo is going away."),
+		Entry("should interpolate the name, state and versions",
+			"{{.Name}} is {{.State}} since {{.DeprecatedInVersion}} and goes away in {{.RemovedInVersion}}.",
+			"Foo is Deprecated since v1.2 and goes away in v1.4."),
+		Entry("should interpolate the generic documentation link without a DocURL",
+			"Foo is going away, see {{.DocumentationURL}}",
+			"Foo is going away, see "+deprecationDocURL),
+		Entry("should be returned as is when it is not a valid template", "{{.Name} is going away.", "{{.Name} is going away."),
+		Entry("should be returned as is when it refers to unknown fields", "{{.Version}} is going away.", "{{.Version}} is going away."),
+	)
+
+	It("custom message should interpolate the DocURL of the feature gate", func() {
+		fg := FeatureGate{Name: "Foo", State: Deprecated, Message: "{{.Name}} is going away, see {{.DocumentationURL}}", DocURL: "https://kubevirt.io/user-guide/foo-migration/"}
+		Expect(fg.EffectiveMessage()).To(Equal("Foo is going away, see https://kubevirt.io/user-guide/foo-migration/"))
+	})
+
+	It("should be the message the Passt gate is registered with", func() {
+		fg := FeatureGateInfo(PasstGate)
+		Expect(fg).ToNot(BeNil())
+		Expect(fg.Message).To(Equal(PasstDeprecationMessage))
+		Expect(fg.EffectiveMessage()).To(Equal(PasstDeprecationMessage))
+	})
+})
@@ -0,0 +1,79 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package deprecation
+
+import "sort"
+
+// FeatureGateStore provides the tracked feature gates, allowing to replace the built-in ones,
+// e.g. by a controlled set in tests
+type FeatureGateStore interface {
+	// Lookup returns the feature gate matching the name or one of its aliases case-insensitively,
+	// nil if it is not tracked
+	Lookup(name string) *FeatureGate
+	// All returns a copy of all the feature gates sorted by name
+	All() []FeatureGate
+}
+
+type defaultStore struct{}
+
+// DefaultFeatureGateStore returns the store of the built-in feature gates and the ones added through RegisterFeatureGate
+func DefaultFeatureGateStore() FeatureGateStore {
+	return defaultStore{}
+}
+
+func (defaultStore) Lookup(name string) *FeatureGate {
+	return FeatureGateInfo(name)
+}
+
+func (defaultStore) All() []FeatureGate {
+	return AllFeatureGates()
+}
+
+type staticStore struct {
+	featureGates []FeatureGate
+}
+
+// NewFeatureGateStore returns a store of only the given feature gates, failing if any name or alias
+// refers to more than one of them
+func NewFeatureGateStore(fgs ...FeatureGate) (FeatureGateStore, error) {
+	if err := checkDuplicateFeatureGates(fgs); err != nil {
+		return nil, err
+	}
+
+	store := staticStore{featureGates: append([]FeatureGate(nil), fgs...)}
+	sort.Slice(store.featureGates, func(i, j int) bool {
+		return store.featureGates[i].Name < store.featureGates[j].Name
+	})
+	return store, nil
+}
+
+func (s staticStore) Lookup(name string) *FeatureGate {
+	for _, fg := range s.featureGates {
+		if fg.Matches(name) {
+			fg := fg
+			return &fg
+		}
+	}
+	return nil
+}
+
+func (s staticStore) All() []FeatureGate {
+	return append([]FeatureGate(nil), s.featureGates...)
+}
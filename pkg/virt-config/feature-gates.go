@@ -19,7 +19,15 @@
 
 package virtconfig
 
-import "kubevirt.io/kubevirt/pkg/virt-config/deprecation"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kubevirt.io/client-go/log"
+
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
+)
 
 /*
  This module is intended for determining whether an optional feature is enabled or not at the cluster-level.
@@ -80,25 +88,377 @@ const (
 	AlignCPUsGate = "AlignCPUs"
 )
 
+// activeFeatureGates are the feature gates declared above, the ones not tracked by the deprecation package
+var activeFeatureGates = []string{
+	ExpandDisksGate,
+	CPUManager,
+	NUMAFeatureGate,
+	IgnitionGate,
+	HypervStrictCheckGate,
+	SidecarGate,
+	GPUGate,
+	HostDevicesGate,
+	SnapshotGate,
+	VMExportGate,
+	HotplugVolumesGate,
+	HostDiskGate,
+	VirtIOFSGate,
+	DownwardMetricsFeatureGate,
+	Root,
+	ClusterProfiler,
+	WorkloadEncryptionSEV,
+	DockerSELinuxMCSWorkaround,
+	VSOCKGate,
+	DisableCustomSELinuxPolicy,
+	KubevirtSeccompProfile,
+	DisableMediatedDevicesHandling,
+	HotplugNetworkIfacesGate,
+	PersistentReservation,
+	VMPersistentState,
+	Multiarchitecture,
+	VMLiveUpdateFeaturesGate,
+	BochsDisplayForEFIGuests,
+	NetworkBindingPlugingsGate,
+	AutoResourceLimitsGate,
+	CommonInstancetypesDeploymentGate,
+	AlignCPUsGate,
+}
+
+// SetFeatureGateStore replaces the store the tracked feature gates are looked up in, the default
+// one providing the built-in feature gates
+func (config *ClusterConfig) SetFeatureGateStore(store deprecation.FeatureGateStore) {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+	config.featureGateStore = store
+}
+
+func (config *ClusterConfig) featureGates() deprecation.FeatureGateStore {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+	return config.featureGatesLocked()
+}
+
+// featureGatesLocked must be called with the config lock held
+func (config *ClusterConfig) featureGatesLocked() deprecation.FeatureGateStore {
+	if config.featureGateStore == nil {
+		return deprecation.DefaultFeatureGateStore()
+	}
+	return config.featureGateStore
+}
+
 func (config *ClusterConfig) isFeatureGateEnabled(featureGate string) bool {
-	deprecatedFeature := deprecation.FeatureGateInfo(featureGate)
-	if deprecatedFeature != nil {
-		switch state := deprecatedFeature.State; state {
-		case deprecation.GA:
+	enabled, _ := config.FeatureGateStatus(featureGate)
+	return enabled
+}
+
+// FeatureGateStatus returns whether the feature gate is enabled together with the tracked record
+// the decision was based on, nil for untracked feature gates
+func (config *ClusterConfig) FeatureGateStatus(featureGate string) (bool, *deprecation.FeatureGate) {
+	info := config.featureGates().Lookup(featureGate)
+	enabled, configuredName := featureGateMatch(featureGate, info, config.GetConfig().DeveloperConfiguration.FeatureGates, nil)
+	canonicalName := featureGate
+	if info != nil {
+		canonicalName = info.Name
+	}
+	if warning := featureGateNameWarning(canonicalName, configuredName); warning != "" && config.markFeatureGateNameLogged(configuredName) {
+		log.Log.Warning(warning)
+	}
+	if enabled && info != nil && info.IsDeprecated() && config.markDeprecatedFeatureGateLogged(info.Name) {
+		logger := log.Log.With("featureGate", info.Name, "state", info.State, "message", info.EffectiveMessage())
+		if info.State == deprecation.PendingRemoval {
+			logger.Warning("feature gate pending removal is enabled, it stops working in an upcoming release")
+		} else {
+			logger.Warning("deprecated feature gate is enabled")
+		}
+	}
+	return enabled, info
+}
+
+// markDeprecatedFeatureGateLogged returns true only the first time it is called for a feature gate
+// since the current config was loaded
+func (config *ClusterConfig) markDeprecatedFeatureGateLogged(featureGate string) bool {
+	return config.markLogged(&config.loggedDeprecatedFeatureGates, featureGate)
+}
+
+// markFeatureGateNameLogged returns true only the first time it is called for a configured feature
+// gate name since the current config was loaded
+func (config *ClusterConfig) markFeatureGateNameLogged(configuredName string) bool {
+	return config.markLogged(&config.loggedFeatureGateNames, configuredName)
+}
+
+func (config *ClusterConfig) markLogged(logged *map[string]struct{}, key string) bool {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+
+	if _, exists := (*logged)[key]; exists {
+		return false
+	}
+	if *logged == nil {
+		*logged = map[string]struct{}{}
+	}
+	(*logged)[key] = struct{}{}
+	return true
+}
+
+// ValidateFeatureGates returns an error for each configured feature gate that is Discontinued,
+// since these are silently ignored. Deprecated feature gates are still functional and do not produce errors.
+func (config *ClusterConfig) ValidateFeatureGates() []error {
+	return validateFeatureGates(config.GetConfig().DeveloperConfiguration.FeatureGates, config.featureGates().Lookup)
+}
+
+// EnabledDeprecatedFeatureGates returns the feature gates in the Deprecated or PendingRemoval state which are enabled in the config
+func (config *ClusterConfig) EnabledDeprecatedFeatureGates() []deprecation.FeatureGate {
+	return deprecatedFeatureGatesUsed(config.GetConfig().DeveloperConfiguration.FeatureGates, config.featureGates().Lookup)
+}
+
+// HasEnabledDeprecatedFeatureGate returns whether any feature gate in the Deprecated or PendingRemoval
+// state is enabled, stopping at the first one found
+func (config *ClusterConfig) HasEnabledDeprecatedFeatureGate() bool {
+	for _, fg := range config.featureGates().All() {
+		if fg.IsDeprecated() && config.isFeatureGateEnabled(fg.Name) {
 			return true
-		case deprecation.Discontinued:
-			return false
 		}
 	}
+	return false
+}
+
+// OnDeprecatedFeatureGateUsed registers a callback which is invoked once for every enabled feature gate in the
+// Deprecated or PendingRemoval state, each time a new version of the cluster config is loaded. The callbacks
+// are invoked synchronously, in the order they were registered, by the GetConfig call loading the new version
+func (config *ClusterConfig) OnDeprecatedFeatureGateUsed(cb DeprecatedFeatureGateUsedFn) {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+	config.deprecatedFeatureGateUsedCallback = append(config.deprecatedFeatureGateUsedCallback, cb)
+}
+
+// deprecatedFeatureGatesNotifier returns a func invoking the callbacks with the deprecated feature gates of
+// the current config. It must be called with the config lock held, the returned func without it
+func (config *ClusterConfig) deprecatedFeatureGatesNotifier() func() {
+	callbacks := config.deprecatedFeatureGateUsedCallback
+	if len(callbacks) == 0 {
+		return func() {}
+	}
+
+	deprecatedFeatureGates := deprecatedFeatureGatesUsed(config.lastValidConfig.DeveloperConfiguration.FeatureGates, config.featureGatesLocked().Lookup)
+	return func() {
+		for _, fg := range deprecatedFeatureGates {
+			for _, callback := range callbacks {
+				callback(fg)
+			}
+		}
+	}
+}
 
-	for _, fg := range config.GetConfig().DeveloperConfiguration.FeatureGates {
-		if fg == featureGate {
+func deprecatedFeatureGatesUsed(configuredFeatureGates []string, featureGateInfo func(string) *deprecation.FeatureGate) []deprecation.FeatureGate {
+	var used []deprecation.FeatureGate
+	seen := map[string]struct{}{}
+	for _, fg := range configuredFeatureGates {
+		info := featureGateInfo(fg)
+		if info == nil || !info.IsDeprecated() {
+			continue
+		}
+		if _, exists := seen[info.Name]; exists {
+			continue
+		}
+		seen[info.Name] = struct{}{}
+		used = append(used, *info)
+	}
+	return used
+}
+
+// DeprecationWarnings returns a warning for each enabled feature gate in the Deprecated or PendingRemoval
+// state, the latter's default message escalating the wording, complementing the blocking errors of ValidateFeatureGates
+func (config *ClusterConfig) DeprecationWarnings() []string {
+	return deprecationWarnings(config.GetConfig().DeveloperConfiguration.FeatureGates, config.featureGates().Lookup)
+}
+
+func deprecationWarnings(configuredFeatureGates []string, featureGateInfo func(string) *deprecation.FeatureGate) []string {
+	var warnings []string
+	for _, fg := range deprecatedFeatureGatesUsed(configuredFeatureGates, featureGateInfo) {
+		warnings = append(warnings, fg.EffectiveMessage())
+	}
+	return warnings
+}
+
+// UnknownFeatureGates returns the configured feature gates which are neither active nor tracked
+// by the deprecation package, e.g. because of a typo. GA gates are tracked, setting them is a no-op but not unknown
+func (config *ClusterConfig) UnknownFeatureGates() []string {
+	return unknownFeatureGates(config.GetConfig().DeveloperConfiguration.FeatureGates, config.featureGates().Lookup)
+}
+
+func unknownFeatureGates(configuredFeatureGates []string, featureGateInfo func(string) *deprecation.FeatureGate) []string {
+	var unknown []string
+	for _, fg := range configuredFeatureGates {
+		if featureGateInfo(fg) == nil && !isActiveFeatureGate(fg) {
+			unknown = append(unknown, fg)
+		}
+	}
+	return unknown
+}
+
+// FeatureGateTransitionWarnings compares the states of the feature gates observed before an upgrade with the
+// tracked ones and returns a warning, sorted by feature gate, for each one whose state regressed, e.g. from
+// Deprecated to Discontinued. Feature gates which are no longer tracked are not reported
+func (config *ClusterConfig) FeatureGateTransitionWarnings(previousState map[string]deprecation.State) []string {
+	return featureGateTransitionWarnings(previousState, config.featureGates().Lookup)
+}
+
+func featureGateTransitionWarnings(previousState map[string]deprecation.State, featureGateInfo func(string) *deprecation.FeatureGate) []string {
+	names := make([]string, 0, len(previousState))
+	for name := range previousState {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []string
+	for _, name := range names {
+		info := featureGateInfo(name)
+		if info == nil || !deprecation.IsRegression(previousState[name], info.State) {
+			continue
+		}
+		warning := fmt.Sprintf("feature gate %s went from the %q to the %q state", info.Name, previousState[name], info.State)
+		if info.State == deprecation.Discontinued {
+			warning += ", the functionality it enabled is no longer available"
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// ActiveFeatureGates returns the configured feature gates which change the behavior, i.e. the enabled ones
+// which are neither GA, as these are no-ops, nor Discontinued nor unknown. Deprecated gates are included
+func (config *ClusterConfig) ActiveFeatureGates() []string {
+	return activeConfiguredFeatureGates(config.GetConfig().DeveloperConfiguration.FeatureGates, nil, config.featureGates().Lookup)
+}
+
+func activeConfiguredFeatureGates(configuredFeatureGates, disabledFeatureGates []string, featureGateInfo func(string) *deprecation.FeatureGate) []string {
+	var active []string
+	seen := map[string]struct{}{}
+	for _, fg := range configuredFeatureGates {
+		info := featureGateInfo(fg)
+		key := strings.ToLower(fg)
+		if info != nil {
+			if info.State == deprecation.GA || info.State == deprecation.Discontinued {
+				continue
+			}
+			key = strings.ToLower(info.Name)
+		} else if !isActiveFeatureGate(fg) {
+			continue
+		}
+		if _, exists := seen[key]; exists || !featureGateEnabled(fg, info, configuredFeatureGates, disabledFeatureGates) {
+			continue
+		}
+		seen[key] = struct{}{}
+		active = append(active, fg)
+	}
+	return active
+}
+
+func isActiveFeatureGate(featureGate string) bool {
+	for _, fg := range activeFeatureGates {
+		if strings.EqualFold(fg, featureGate) {
 			return true
 		}
 	}
 	return false
 }
 
+func validateFeatureGates(configuredFeatureGates []string, featureGateInfo func(string) *deprecation.FeatureGate) []error {
+	var errs []error
+	for _, fg := range configuredFeatureGates {
+		info := featureGateInfo(fg)
+		if info != nil && info.State == deprecation.Discontinued {
+			errs = append(errs, fmt.Errorf("feature gate %s is discontinued: %s", info.Name, info.EffectiveMessage()))
+		}
+	}
+	return errs
+}
+
+// ValidateFeatureGateDependencies returns an error for each enabled feature gate whose required
+// feature gates are not enabled. GA prerequisites are always enabled and thus always satisfied.
+func (config *ClusterConfig) ValidateFeatureGateDependencies() []error {
+	return validateFeatureGateDependencies(config.GetConfig().DeveloperConfiguration.FeatureGates, nil, config.featureGates().Lookup)
+}
+
+func validateFeatureGateDependencies(configuredFeatureGates, disabledFeatureGates []string, featureGateInfo func(string) *deprecation.FeatureGate) []error {
+	var errs []error
+	seen := map[string]struct{}{}
+	for _, fg := range configuredFeatureGates {
+		info := featureGateInfo(fg)
+		if info == nil || len(info.Requires) == 0 || !featureGateEnabled(info.Name, info, configuredFeatureGates, disabledFeatureGates) {
+			continue
+		}
+		if _, exists := seen[info.Name]; exists {
+			continue
+		}
+		seen[info.Name] = struct{}{}
+
+		for _, required := range info.Requires {
+			if !featureGateEnabled(required, featureGateInfo(required), configuredFeatureGates, disabledFeatureGates) {
+				errs = append(errs, fmt.Errorf("feature gate %s requires feature gate %s to be enabled", info.Name, required))
+			}
+		}
+	}
+	return errs
+}
+
+// featureGateEnabled decides based on the feature gate state whether it is enabled.
+// GA feature gates are always enabled, Discontinued ones never are, and gates in any other
+// state (Alpha, Beta, Deprecated, PendingRemoval or untracked) must be present in the configured feature gates,
+// unless they are DefaultEnabled, in which case they are enabled as long as they are not present in
+// the disabled feature gates. No feature gate is DefaultEnabled yet, so the disabled feature gates
+// aren't part of the configuration and the cluster config passes none.
+// Configured feature gates are matched case-insensitively and by their aliases.
+func featureGateEnabled(featureGate string, info *deprecation.FeatureGate, configuredFeatureGates, disabledFeatureGates []string) bool {
+	enabled, _ := featureGateMatch(featureGate, info, configuredFeatureGates, disabledFeatureGates)
+	return enabled
+}
+
+// featureGateMatch is featureGateEnabled additionally returning the configured or disabled name the
+// decision was based on, empty when none matched
+func featureGateMatch(featureGate string, info *deprecation.FeatureGate, configuredFeatureGates, disabledFeatureGates []string) (bool, string) {
+	matches := func(name string) bool { return strings.EqualFold(name, featureGate) }
+	if info != nil {
+		switch state := info.State; state {
+		case deprecation.GA:
+			return true, ""
+		case deprecation.Discontinued:
+			return false, ""
+		}
+		matches = info.Matches
+		if info.DefaultEnabled {
+			disabledName, disabled := findFeatureGate(matches, disabledFeatureGates)
+			return !disabled, disabledName
+		}
+	}
+
+	configuredName, configured := findFeatureGate(matches, configuredFeatureGates)
+	return configured, configuredName
+}
+
+// findFeatureGate returns the first of the feature gates that matches
+func findFeatureGate(matches func(string) bool, featureGates []string) (string, bool) {
+	for _, fg := range featureGates {
+		if matches(fg) {
+			return fg, true
+		}
+	}
+	return "", false
+}
+
+// featureGateNameWarning returns a warning pointing to the canonical name of the feature gate when the
+// configured name differs from it, an empty string otherwise
+func featureGateNameWarning(canonicalName, configuredName string) string {
+	switch {
+	case configuredName == "" || configuredName == canonicalName:
+		return ""
+	case strings.EqualFold(configuredName, canonicalName):
+		return fmt.Sprintf("feature gate %q is configured with a different casing, did you mean %q?", configuredName, canonicalName)
+	default:
+		return fmt.Sprintf("feature gate %q was renamed, please use %q instead", configuredName, canonicalName)
+	}
+}
+
 func (config *ClusterConfig) ExpandDisksEnabled() bool {
 	return config.isFeatureGateEnabled(ExpandDisksGate)
 }
@@ -0,0 +1,204 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package virtconfig
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
+)
+
+var _ = Describe("feature gates", func() {
+	const testGate = "TestGate"
+
+	DescribeTable("enablement per feature gate state", func(state string, configuredFeatureGates []string, expected bool) {
+		info := &deprecation.FeatureGate{Name: testGate, State: deprecation.State(state)}
+		Expect(featureGateEnabled(testGate, info, configuredFeatureGates, nil)).To(Equal(expected))
+	},
+		Entry("Alpha gate not set should be disabled", deprecation.Alpha, nil, false),
+		Entry("Alpha gate set should be enabled", deprecation.Alpha, []string{testGate}, true),
+		Entry("Beta gate not set should be disabled", deprecation.Beta, nil, false),
+		Entry("Beta gate set should be enabled", deprecation.Beta, []string{testGate}, true),
+		Entry("GA gate not set should be enabled", deprecation.GA, nil, true),
+		Entry("GA gate set should be enabled", deprecation.GA, []string{testGate}, true),
+		Entry("Deprecated gate not set should be disabled", deprecation.Deprecated, nil, false),
+		Entry("Deprecated gate set should be enabled", deprecation.Deprecated, []string{testGate}, true),
+		Entry("PendingRemoval gate not set should be disabled", deprecation.PendingRemoval, nil, false),
+		Entry("PendingRemoval gate set should be enabled", deprecation.PendingRemoval, []string{testGate}, true),
+		Entry("Discontinued gate not set should be disabled", deprecation.Discontinued, nil, false),
+		Entry("Discontinued gate set should be disabled", deprecation.Discontinued, []string{testGate}, false),
+	)
+
+	It("untracked feature gate should only be enabled when set", func() {
+		Expect(featureGateEnabled(testGate, nil, nil, nil)).To(BeFalse())
+		Expect(featureGateEnabled(testGate, nil, []string{testGate}, nil)).To(BeTrue())
+	})
+
+	DescribeTable("enablement of default enabled feature gates", func(defaultEnabled bool, configured, disabled []string, expected bool) {
+		info := &deprecation.FeatureGate{Name: testGate, State: deprecation.Beta, DefaultEnabled: defaultEnabled}
+		Expect(featureGateEnabled(testGate, info, configured, disabled)).To(Equal(expected))
+	},
+		Entry("default on gate not set should be enabled", true, nil, nil, true),
+		Entry("default on gate explicitly disabled should be disabled", true, nil, []string{testGate}, false),
+		Entry("default on gate disabled with a different casing should be disabled", true, nil, []string{"testgate"}, false),
+		Entry("default on gate both set and disabled should be disabled", true, []string{testGate}, []string{testGate}, false),
+		Entry("default off gate not set should be disabled", false, nil, nil, false),
+		Entry("default off gate explicitly enabled should be enabled", false, []string{testGate}, nil, true),
+		Entry("default off gate listed as disabled should stay disabled", false, nil, []string{testGate}, false),
+	)
+
+	DescribeTable("configured feature gates should be matched case-insensitively", func(featureGate, configured string) {
+		Expect(featureGateEnabled(featureGate, deprecation.FeatureGateInfo(featureGate), []string{configured}, nil)).To(BeTrue())
+	},
+		Entry("GA gate", deprecation.LiveMigrationGate, "livemigration"),
+		Entry("Deprecated gate", deprecation.PasstGate, "PASST"),
+		Entry("untracked gate", ExpandDisksGate, "expandDisks"),
+	)
+
+	Context("aliases", func() {
+		const aliasGate = "OldTestGate"
+
+		DescribeTable("should resolve to the same behavior as the canonical name", func(state string, configured []string, expected bool) {
+			info := &deprecation.FeatureGate{Name: testGate, State: deprecation.State(state), Aliases: []string{aliasGate}}
+			Expect(featureGateEnabled(testGate, info, configured, nil)).To(Equal(expected))
+			Expect(featureGateEnabled(aliasGate, info, configured, nil)).To(Equal(expected))
+		},
+			Entry("GA gate not set", deprecation.GA, nil, true),
+			Entry("GA gate set by its alias", deprecation.GA, []string{aliasGate}, true),
+			Entry("Beta gate not set", deprecation.Beta, nil, false),
+			Entry("Beta gate set by its alias", deprecation.Beta, []string{"oldtestgate"}, true),
+			Entry("Beta gate set by its canonical name", deprecation.Beta, []string{testGate}, true),
+		)
+	})
+
+	DescribeTable("featureGateNameWarning should point to the canonical name", func(configuredName, expected string) {
+		Expect(featureGateNameWarning(testGate, configuredName)).To(Equal(expected))
+	},
+		Entry("not configured", "", ""),
+		Entry("canonical name", testGate, ""),
+		Entry("different casing", "testgate", `feature gate "testgate" is configured with a different casing, did you mean "TestGate"?`),
+		Entry("alias", "OldTestGate", `feature gate "OldTestGate" was renamed, please use "TestGate" instead`),
+	)
+
+	It("deprecated feature gates should be logged once per config", func() {
+		config := &ClusterConfig{lock: &sync.Mutex{}}
+		Expect(config.markDeprecatedFeatureGateLogged(deprecation.PasstGate)).To(BeTrue())
+		Expect(config.markDeprecatedFeatureGateLogged(deprecation.PasstGate)).To(BeFalse())
+		Expect(config.markDeprecatedFeatureGateLogged(deprecation.MacvtapGate)).To(BeTrue())
+
+		config.loggedDeprecatedFeatureGates = nil
+		Expect(config.markDeprecatedFeatureGateLogged(deprecation.PasstGate)).To(BeTrue())
+	})
+
+	DescribeTable("unknownFeatureGates should only return the unrecognized feature gates", func(configured, expected []string) {
+		Expect(unknownFeatureGates(configured, deprecation.FeatureGateInfo)).To(Equal(expected))
+	},
+		Entry("active gate", []string{ExpandDisksGate}, nil),
+		Entry("active gate with a different casing", []string{"expanddisks"}, nil),
+		Entry("tracked gate", []string{deprecation.LiveMigrationGate, deprecation.PasstGate}, nil),
+		Entry("typo", []string{ExpandDisksGate, "ExpandDisk"}, []string{"ExpandDisk"}),
+	)
+
+	Context("validateFeatureGates", func() {
+		const discontinuedGate = "DiscontinuedGate"
+
+		featureGateInfo := func(name string) *deprecation.FeatureGate {
+			switch name {
+			case discontinuedGate:
+				return &deprecation.FeatureGate{Name: discontinuedGate, State: deprecation.Discontinued, Message: "DiscontinuedGate is gone."}
+			case deprecation.PasstGate:
+				return &deprecation.FeatureGate{Name: deprecation.PasstGate, State: deprecation.Deprecated, Message: "Passt is deprecated."}
+			}
+			return nil
+		}
+
+		It("should return an error for each enabled Discontinued feature gate", func() {
+			errs := validateFeatureGates([]string{ExpandDisksGate, discontinuedGate}, featureGateInfo)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring("DiscontinuedGate is gone.")))
+		})
+
+		It("should not return errors for Deprecated feature gates", func() {
+			Expect(validateFeatureGates([]string{deprecation.PasstGate}, featureGateInfo)).To(BeEmpty())
+		})
+
+		It("should only return warnings for Deprecated feature gates", func() {
+			configured := []string{deprecation.LiveMigrationGate, discontinuedGate, deprecation.PasstGate, ExpandDisksGate}
+			Expect(deprecationWarnings(configured, featureGateInfo)).To(Equal([]string{"Passt is deprecated."}))
+		})
+
+		DescribeTable("should escalate the warnings by state", func(state string, expectedWarning string) {
+			info := func(name string) *deprecation.FeatureGate {
+				return &deprecation.FeatureGate{Name: testGate, State: deprecation.State(state)}
+			}
+			Expect(validateFeatureGates([]string{testGate}, info)).To(BeEmpty())
+
+			warnings := deprecationWarnings([]string{testGate}, info)
+			if expectedWarning == "" {
+				Expect(warnings).To(BeEmpty())
+			} else {
+				Expect(warnings).To(ConsistOf(ContainSubstring(expectedWarning)))
+			}
+		},
+			Entry("Beta gate should not warn", deprecation.Beta, ""),
+			Entry("Deprecated gate should warn it is deprecated", deprecation.Deprecated, "feature gate TestGate is deprecated"),
+			Entry("PendingRemoval gate should warn it is about to be removed", deprecation.PendingRemoval,
+				"feature gate TestGate is about to be removed (feature state is \"PendingRemoval\"), it stops working in an upcoming release"),
+		)
+	})
+
+	Context("validateFeatureGateDependencies", func() {
+		const (
+			dependentGate    = "DependentGate"
+			prerequisiteGate = "PrerequisiteGate"
+			gaGate           = "GAGate"
+		)
+
+		featureGateInfo := func(name string) *deprecation.FeatureGate {
+			switch name {
+			case dependentGate:
+				return &deprecation.FeatureGate{Name: dependentGate, State: deprecation.Beta, Requires: []string{prerequisiteGate, gaGate}}
+			case prerequisiteGate:
+				return &deprecation.FeatureGate{Name: prerequisiteGate, State: deprecation.Beta}
+			case gaGate:
+				return &deprecation.FeatureGate{Name: gaGate, State: deprecation.GA}
+			}
+			return nil
+		}
+
+		DescribeTable("should flag enabled feature gates without their prerequisites", func(configured []string, expected []string) {
+			var messages []string
+			for _, err := range validateFeatureGateDependencies(configured, nil, featureGateInfo) {
+				messages = append(messages, err.Error())
+			}
+			Expect(messages).To(Equal(expected))
+		},
+			Entry("prerequisite enabled", []string{dependentGate, prerequisiteGate}, nil),
+			Entry("prerequisite missing", []string{dependentGate},
+				[]string{"feature gate DependentGate requires feature gate PrerequisiteGate to be enabled"}),
+			Entry("dependent gate configured twice", []string{dependentGate, "dependentgate"},
+				[]string{"feature gate DependentGate requires feature gate PrerequisiteGate to be enabled"}),
+			Entry("dependent gate not enabled", []string{prerequisiteGate}, nil),
+		)
+	})
+})
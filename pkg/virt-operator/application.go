@@ -60,6 +60,7 @@ import (
 	"kubevirt.io/kubevirt/pkg/service"
 	clusterutil "kubevirt.io/kubevirt/pkg/util/cluster"
 	virtconfig "kubevirt.io/kubevirt/pkg/virt-config"
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
 	"kubevirt.io/kubevirt/pkg/virt-controller/leaderelectionconfig"
 	install "kubevirt.io/kubevirt/pkg/virt-operator/resource/generate/install"
 	"kubevirt.io/kubevirt/pkg/virt-operator/util"
@@ -67,6 +68,10 @@ import (
 
 const VirtOperator = "virt-operator"
 
+// deprecatedFeatureGateEnabledReason is the reason of the events recorded on the KubeVirt CR for the
+// enabled feature gates in the Deprecated or PendingRemoval state
+const deprecatedFeatureGateEnabledReason = "DeprecatedFeatureGateEnabled"
+
 const (
 	controllerThreads = 3
 
@@ -297,6 +302,7 @@ func Execute() {
 	app.reInitChan = make(chan string, 0)
 	app.clusterConfig.SetConfigModifiedCallback(app.shouldChangeLogVerbosity)
 	app.clusterConfig.SetConfigModifiedCallback(app.shouldUpdateConfigurationMetrics)
+	app.clusterConfig.OnDeprecatedFeatureGateUsed(app.recordDeprecatedFeatureGateUsed)
 
 	// Setup monitoring
 	if err := metrics.SetupMetrics(); err != nil {
@@ -496,7 +502,24 @@ func (app *VirtOperatorApp) shouldChangeLogVerbosity() {
 	}
 }
 
+// recordDeprecatedFeatureGateUsed records a warning event on the KubeVirt CR for the enabled deprecated feature gate
+func (app *VirtOperatorApp) recordDeprecatedFeatureGateUsed(fg deprecation.FeatureGate) {
+	kv := app.clusterConfig.GetConfigFromKubeVirtCR()
+	if kv == nil {
+		return
+	}
+	app.kubeVirtRecorder.Eventf(kv, k8sv1.EventTypeWarning, deprecatedFeatureGateEnabledReason,
+		"Feature gate %s is enabled in the %s state: %s", fg.Name, fg.State, fg.EffectiveMessage())
+}
+
 func (app *VirtOperatorApp) shouldUpdateConfigurationMetrics() {
 	emulationEnabled := app.clusterConfig.GetDeveloperConfigurationUseEmulation()
 	metrics.SetEmulationEnabledMetric(emulationEnabled)
+
+	var deprecatedFeatureGates []string
+	for _, fg := range app.clusterConfig.EnabledDeprecatedFeatureGates() {
+		deprecatedFeatureGates = append(deprecatedFeatureGates, fg.Name)
+	}
+	metrics.SetDeprecatedFeatureGatesEnabledMetric(deprecatedFeatureGates)
+	metrics.SetFeatureGatesConfiguredMetric(app.clusterConfig.GetConfig().DeveloperConfiguration.FeatureGates)
 }
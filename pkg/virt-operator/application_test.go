@@ -24,9 +24,11 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
 	v1 "kubevirt.io/api/core/v1"
 
 	"kubevirt.io/kubevirt/pkg/testutils"
+	"kubevirt.io/kubevirt/pkg/virt-config/deprecation"
 )
 
 var _ = Describe("Reinitialization conditions", func() {
@@ -83,3 +85,22 @@ var _ = Describe("Reinitialization conditions", func() {
 		Entry("when ServiceMonitor is removed and PrometheusRule is introduced", true, false, false, true, true, false, true),
 	)
 })
+
+var _ = Describe("Deprecated feature gates", func() {
+	It("should record a warning event once per config version for each enabled deprecated feature gate", func() {
+		clusterConfig, _, _ := testutils.NewFakeClusterConfigUsingKVConfig(&v1.KubeVirtConfiguration{
+			DeveloperConfiguration: &v1.DeveloperConfiguration{
+				FeatureGates: []string{deprecation.LiveMigrationGate, deprecation.PasstGate},
+			},
+		})
+		recorder := record.NewFakeRecorder(10)
+		app := VirtOperatorApp{clusterConfig: clusterConfig, kubeVirtRecorder: recorder}
+		app.clusterConfig.OnDeprecatedFeatureGateUsed(app.recordDeprecatedFeatureGateUsed)
+
+		app.clusterConfig.GetConfig()
+		app.clusterConfig.GetConfig()
+
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + deprecatedFeatureGateEnabledReason + " Feature gate Passt is enabled in the Deprecated state: ")))
+		Expect(recorder.Events).ToNot(Receive())
+	})
+})
@@ -450,8 +450,8 @@ func featureGatesChanged(currKVSpec, newKVSpec *v1.KubeVirtSpec) bool {
 func warnDeprecatedFeatureGates(featureGates []string) (warnings []string) {
 	for _, featureGate := range featureGates {
 		deprectedFeature := deprecation.FeatureGateInfo(featureGate)
-		if deprectedFeature != nil {
-			warning := deprectedFeature.Message
+		if deprectedFeature != nil && deprectedFeature.State != deprecation.Alpha && deprectedFeature.State != deprecation.Beta {
+			warning := deprectedFeature.EffectiveMessage()
 			warnings = append(warnings, warning)
 			log.Log.Warning(warning)
 		}
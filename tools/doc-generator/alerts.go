@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const alertsHeading = "## Alerts"
+
+// writeAlerts writes the alerting rules after the metrics, each with its severity, summary and expression
+func writeAlerts(w io.Writer, alerts []collector.Alert) {
+	fmt.Fprintln(w, alertsHeading)
+	for _, alert := range alerts {
+		fmt.Fprintln(w, "###", alert.Name)
+		if alert.Severity != "" {
+			fmt.Fprintln(w, sentence(escapeMarkdown(alert.Summary)), "Severity:", alert.Severity+".")
+		} else {
+			fmt.Fprintln(w, sentence(escapeMarkdown(alert.Summary)))
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "```promql")
+		fmt.Fprintln(w, alert.Expr)
+		fmt.Fprintln(w, "```")
+		fmt.Fprintln(w)
+	}
+}
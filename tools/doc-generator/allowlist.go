@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// loadAllowlist reads the metric names of the allowlist file
+func loadAllowlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the allowlist: %v", err)
+	}
+	defer f.Close()
+
+	names, err := parseAllowlist(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the allowlist %s: %v", path, err)
+	}
+	return names, nil
+}
+
+// parseAllowlist returns the metric names listed one per line, skipping blank lines and # comments
+func parseAllowlist(r io.Reader) ([]string, error) {
+	var names []string
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scan.Err()
+}
+
+// allowMetrics keeps only the metrics whose names are allowlisted, failing if an allowlisted
+// name doesn't match any metric so the allowlist doesn't go stale
+func allowMetrics(m collector.List, names []string) (collector.List, error) {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+
+	found := make(map[string]bool, len(names))
+	var kept collector.List
+	for _, met := range m {
+		if allowed[met.Name] {
+			found[met.Name] = true
+			kept = append(kept, met)
+		}
+	}
+
+	var missing []string
+	for name := range allowed {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("allowlisted metrics not found: %s", strings.Join(missing, ", "))
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// metricChange describes how a metric documented in both versions changed
+type metricChange struct {
+	name            string
+	typeChange      [2]string
+	descriptionDiff bool
+}
+
+func (c metricChange) String() string {
+	var parts []string
+	if c.typeChange[0] != c.typeChange[1] {
+		parts = append(parts, fmt.Sprintf("type changed from %s to %s", c.typeChange[0], c.typeChange[1]))
+	}
+	if c.descriptionDiff {
+		parts = append(parts, "description changed")
+	}
+	return fmt.Sprintf("`%s`: %s", c.name, strings.Join(parts, ", "))
+}
+
+// changelog lists the metrics added, removed and changed since the base version of the documentation
+type changelog struct {
+	added   []string
+	removed []string
+	changed []metricChange
+}
+
+// loadBaseMetrics reads the metrics of a previous version of the documentation generated with -format=json
+func loadBaseMetrics(path string) ([]jsonMetric, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var base []jsonMetric
+	if err := json.Unmarshal(content, &base); err != nil {
+		return nil, fmt.Errorf("failed to parse the base metrics of %s, it must be generated with -format=%s: %v", path, formatJSON, err)
+	}
+	return base, nil
+}
+
+// compareVersions compares the current metrics with the base ones, by name, type and description
+func compareVersions(base []jsonMetric, current collector.List) changelog {
+	baseByName := make(map[string]jsonMetric, len(base))
+	for _, m := range base {
+		baseByName[m.Name] = m
+	}
+
+	var changes changelog
+	currentNames := make(map[string]bool, len(current))
+	for _, m := range current {
+		currentNames[m.Name] = true
+		previous, ok := baseByName[m.Name]
+		if !ok {
+			changes.added = append(changes.added, m.Name)
+			continue
+		}
+		change := metricChange{
+			name:            m.Name,
+			typeChange:      [2]string{previous.Type, string(m.Type)},
+			descriptionDiff: previous.Description != m.Description,
+		}
+		if change.typeChange[0] != change.typeChange[1] || change.descriptionDiff {
+			changes.changed = append(changes.changed, change)
+		}
+	}
+	for _, m := range base {
+		if !currentNames[m.Name] {
+			changes.removed = append(changes.removed, m.Name)
+		}
+	}
+
+	sort.Strings(changes.added)
+	sort.Strings(changes.removed)
+	sort.Slice(changes.changed, func(i, j int) bool { return changes.changed[i].name < changes.changed[j].name })
+	return changes
+}
+
+// write writes the changelog as markdown sections, leaving out the empty ones
+func (changes changelog) write(w io.Writer) {
+	if len(changes.added) == 0 && len(changes.removed) == 0 && len(changes.changed) == 0 {
+		fmt.Fprintln(w, "No metric changes.")
+		return
+	}
+
+	writeSection := func(title string, entries []string) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintln(w, "###", title)
+		for _, entry := range entries {
+			fmt.Fprintln(w, "-", entry)
+		}
+		fmt.Fprintln(w)
+	}
+
+	quoted := func(names []string) []string {
+		entries := make([]string, 0, len(names))
+		for _, name := range names {
+			entries = append(entries, "`"+name+"`")
+		}
+		return entries
+	}
+	writeSection("Added", quoted(changes.added))
+	writeSection("Removed", quoted(changes.removed))
+	changed := make([]string, 0, len(changes.changed))
+	for _, change := range changes.changed {
+		changed = append(changed, change.String())
+	}
+	writeSection("Changed", changed)
+}
+
+// writeChangelog writes the changes of the metrics since the base version to the output file, or stdout
+// when unset, independently of the generated documentation
+func writeChangelog(metrics collector.List, basePath string, output string) {
+	base, err := loadBaseMetrics(basePath)
+	exitOnError(err)
+
+	changes := compareVersions(base, metrics)
+	if output == "" || output == stdoutOutput {
+		changes.write(os.Stdout)
+		return
+	}
+
+	file, err := os.Create(output)
+	checkError(err)
+	defer file.Close()
+	changes.write(file)
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+
+	"kubevirt.io/kubevirt/pkg/monitoring/rules"
+)
+
+const (
+	severityAlertLabel     = "severity"
+	summaryAlertAnnotation = "summary"
+)
+
+// Alert is the documentation of an alerting rule
+type Alert struct {
+	Name     string
+	Severity string
+	Summary  string
+	Expr     string
+}
+
+// CollectAlerts returns the alerting rules shipped with KubeVirt sorted by name, the rules namespace
+// being the one the alerts are evaluated against
+func CollectAlerts(rulesNamespace string) ([]Alert, error) {
+	if err := rules.SetupRules(rulesNamespace); err != nil {
+		return nil, err
+	}
+	return alertingRules(rules.ListAlerts())
+}
+
+// alertingRules converts the alerting rules, failing if any of them has no summary
+func alertingRules(alertRules []promv1.Rule) ([]Alert, error) {
+	var alerts []Alert
+	var undocumented []string
+	for _, rule := range alertRules {
+		summary := strings.TrimSpace(rule.Annotations[summaryAlertAnnotation])
+		if summary == "" {
+			undocumented = append(undocumented, rule.Alert)
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Name:     rule.Alert,
+			Severity: rule.Labels[severityAlertLabel],
+			Summary:  summary,
+			Expr:     strings.TrimSpace(rule.Expr.String()),
+		})
+	}
+
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("the following alerts have an empty summary: %s", strings.Join(undocumented, ", "))
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Name < alerts[j].Name })
+	return alerts, nil
+}
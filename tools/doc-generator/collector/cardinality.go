@@ -0,0 +1,22 @@
+package collector
+
+// DefaultHighCardinalityLabels are the label keys taking a value per node, VMI or pod, which grow
+// the series of the metrics carrying them along with the cluster
+var DefaultHighCardinalityLabels = []string{"name", "node", "pod"}
+
+// markHighCardinality sets the high cardinality labels of the metrics carrying any of the label keys
+func markHighCardinality(metrics List, labels []string) {
+	highCardinality := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		highCardinality[label] = true
+	}
+
+	for i := range metrics {
+		metrics[i].HighCardinalityLabels = nil
+		for _, label := range metrics[i].Labels {
+			if highCardinality[label] {
+				metrics[i].HighCardinalityLabels = append(metrics[i].HighCardinalityLabels, label)
+			}
+		}
+	}
+}
@@ -0,0 +1,555 @@
+// Package collector assembles the metrics exposed by the KubeVirt components, for doc-generator
+// and any other tooling documenting them
+package collector
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+	"github.com/machadovilaca/operator-observability/pkg/operatorrules"
+	"github.com/prometheus/client_golang/prometheus"
+
+	domainstats "kubevirt.io/kubevirt/pkg/monitoring/domainstats/prometheus" // import for prometheus metrics
+	virt_api "kubevirt.io/kubevirt/pkg/monitoring/metrics/virt-api"
+	virt_controller "kubevirt.io/kubevirt/pkg/monitoring/metrics/virt-controller"
+	virt_operator "kubevirt.io/kubevirt/pkg/monitoring/metrics/virt-operator"
+	"kubevirt.io/kubevirt/pkg/monitoring/rules"
+	_ "kubevirt.io/kubevirt/pkg/virt-controller/watch"
+)
+
+// Options configures the sources the metrics are collected from
+type Options struct {
+	// Endpoints are the URLs of live metrics endpoints to scrape, the in-process fake collectors are scraped when empty
+	Endpoints []string
+	// Timeout of scraping each endpoint, defaults to DefaultEndpointTimeout
+	Timeout time.Duration
+	// Retries is the number of times scraping an endpoint is retried on connection and server errors
+	Retries int
+	// Prefixes of the metric names to collect from the endpoints, defaults to DefaultPrefix
+	Prefixes []string
+	// StrictPrefix fails on the scraped metrics not starting with any of the prefixes instead of leaving
+	// them out, except the process and Go runtime metrics of the Prometheus client
+	StrictPrefix bool
+	// RulesNamespace is the namespace the recording rules are evaluated against
+	RulesNamespace string
+	// Overrides maps metric names to the descriptions replacing their HELP text
+	Overrides map[string]string
+	// IDs maps metric names to the stable IDs anchoring their documentation, surviving renames
+	IDs map[string]string
+	// SourcePaths maps metric names to the repository relative paths of the files registering them
+	SourcePaths map[string]string
+	// HighCardinalityLabels are the label keys marking the metrics carrying them as high cardinality, none are marked when empty
+	HighCardinalityLabels []string
+	// Samples keeps a redacted example of the scraped samples of each metric
+	Samples bool
+	// Warnings receives the inconsistencies found while assembling the metrics, defaults to os.Stderr
+	Warnings io.Writer
+	// Verbose receives the details of the assembly, e.g. the number of metrics per source, nothing is written when nil
+	Verbose io.Writer
+}
+
+// CollectMetrics assembles the metrics exposed by KubeVirt: the metrics scraped from the endpoints,
+// the ones not included in the endpoints by default and the recording rules
+func CollectMetrics(opts Options) (List, error) {
+	opts = opts.withDefaults()
+
+	// scrape before setting up the component metrics, which can't be collected without their informers
+	expositions, scraped, err := scrape(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range checkHardcodedMetrics(hardcodedMetrics(), scraped) {
+		fmt.Fprintln(opts.Warnings, "warning:", warning)
+	}
+	if err := checkHardcodedTypes(hardcodedMetrics(), scraped); err != nil {
+		return nil, err
+	}
+
+	metrics, err := getMetricsNotIncludeInEndpointByDefault(opts.RulesNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Verbose != nil {
+		logSources(opts.Verbose, len(expositions), scraped, metrics)
+	}
+
+	metrics = append(metrics, scraped...)
+	sort.Sort(metrics)
+	if err := metrics.removeDuplicates(); err != nil {
+		return nil, err
+	}
+
+	if err := applyOverrides(metrics, opts.Overrides); err != nil {
+		return nil, err
+	}
+	if err := applyIDs(metrics, opts.IDs); err != nil {
+		return nil, err
+	}
+	if err := applySourcePaths(metrics, opts.SourcePaths); err != nil {
+		return nil, err
+	}
+	resolveDerivedFrom(metrics)
+	markHighCardinality(metrics, opts.HighCardinalityLabels)
+	if opts.Samples {
+		redactExamples(metrics)
+	} else {
+		for i := range metrics {
+			metrics[i].Example = nil
+		}
+	}
+
+	if err := checkPhaseCount(metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// ScrapeMetrics returns only the metrics exposed by the endpoints, or by the in-process fake collectors
+// when there are none, leaving out the metrics not included in the endpoints by default and the recording rules
+func ScrapeMetrics(opts Options) (List, error) {
+	_, scraped, err := scrape(opts.withDefaults())
+	return scraped, err
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultEndpointTimeout
+	}
+	if len(opts.Prefixes) == 0 {
+		opts.Prefixes = []string{DefaultPrefix}
+	}
+	if opts.Warnings == nil {
+		opts.Warnings = os.Stderr
+	}
+	return opts
+}
+
+func scrape(opts Options) ([]exposition, List, error) {
+	expositions, err := scrapeEndpoints(opts.Endpoints, opts.Timeout, opts.Retries, opts.Verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var scraped List
+	if !opts.StrictPrefix {
+		if err := parseExpositions(expositions, &scraped, opts.Prefixes); err != nil {
+			return nil, nil, err
+		}
+		return expositions, scraped, nil
+	}
+
+	var skipped []string
+	if err := parseExpositionsSkipping(expositions, &scraped, opts.Prefixes, func(name string) { skipped = append(skipped, name) }); err != nil {
+		return nil, nil, err
+	}
+	if err := unprefixedError(skipped, opts.Prefixes); err != nil {
+		return nil, nil, err
+	}
+	return expositions, scraped, nil
+}
+
+// Metric is the documentation of a metric family
+type Metric struct {
+	Name        string
+	Description string
+	Type        MetricType
+	Unit        string
+	Labels      []string
+	Buckets     []float64
+	Quantiles   []float64
+	Stability   Stability
+	// Source is the component owning the metric, only known for the metrics not parsed from the endpoint
+	Source string
+	// Overridden is set when the description was replaced through Options.Overrides
+	Overridden bool
+	// ID is the stable ID set through Options.IDs, empty when the documentation is anchored by the name only
+	ID string
+	// DerivedFrom are the documented metrics the expression of a recording rule refers to
+	DerivedFrom []string
+	// DeprecatedInVersion and RemovedInVersion are the versions of the "[deprecated:v1.2 removed:v1.4]"
+	// HELP annotation, empty when unknown
+	DeprecatedInVersion string
+	RemovedInVersion    string
+	// DefinedIn is the repository relative path set through Options.SourcePaths, empty when unknown
+	DefinedIn string
+	// HighCardinalityLabels are the labels of the metric among Options.HighCardinalityLabels, sorted
+	HighCardinalityLabels []string
+	// Example is the first sample scraped for the metric, with the identifiers redacted, only kept with
+	// Options.Samples
+	Example *Sample
+}
+
+// addLabels merges the given label keys into the sorted set of the metric labels
+func (m *Metric) addLabels(labels ...string) {
+	for _, label := range labels {
+		i := sort.SearchStrings(m.Labels, label)
+		if i < len(m.Labels) && m.Labels[i] == label {
+			continue
+		}
+		m.Labels = append(m.Labels, "")
+		copy(m.Labels[i+1:], m.Labels[i:])
+		m.Labels[i] = label
+	}
+}
+
+// List is a list of metrics, sortable by name
+type List []Metric
+
+// Len implements sort.Interface.Len
+func (m List) Len() int {
+	return len(m)
+}
+
+// Less implements sort.Interface.Less
+func (m List) Less(i, j int) bool {
+	return m[i].Name < m[j].Name
+}
+
+// Swap implements sort.Interface.Swap
+func (m List) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+// ByType is a list of metrics sortable by type, gauges first, then counters, histograms and summaries,
+// and by name within each type
+type ByType List
+
+// Len implements sort.Interface.Len
+func (m ByType) Len() int {
+	return len(m)
+}
+
+// Less implements sort.Interface.Less
+func (m ByType) Less(i, j int) bool {
+	if ri, rj := typeRank(m[i].Type), typeRank(m[j].Type); ri != rj {
+		return ri < rj
+	}
+	return m[i].Name < m[j].Name
+}
+
+// Swap implements sort.Interface.Swap
+func (m ByType) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+// removeDuplicates collapses identical entries of the sorted list and fails
+// when the same metric name is defined more than once with different content
+func (m *List) removeDuplicates() error {
+	var conflicts []string
+	for i := 0; i < len(*m)-1; i++ {
+		current, next := (*m)[i], (*m)[i+1]
+		if current.Name != next.Name {
+			continue
+		}
+
+		if current.Description != next.Description || current.Type != next.Type || current.Unit != next.Unit || current.Stability != next.Stability {
+			conflicts = append(conflicts, fmt.Sprintf("%s: {description: %q, type: %q, unit: %q, stability: %q} != {description: %q, type: %q, unit: %q, stability: %q}",
+				current.Name, current.Description, current.Type, current.Unit, current.Stability, next.Description, next.Type, next.Unit, next.Stability))
+		}
+
+		if (*m)[i+1].Source == "" {
+			(*m)[i+1].Source = current.Source
+		}
+		if len((*m)[i+1].DerivedFrom) == 0 {
+			(*m)[i+1].DerivedFrom = current.DerivedFrom
+		}
+		if (*m)[i+1].Example == nil {
+			(*m)[i+1].Example = current.Example
+		}
+		(*m)[i+1].addLabels(current.Labels...)
+		(*m)[i+1].addBuckets(current.Buckets...)
+		(*m)[i+1].addQuantiles(current.Quantiles...)
+		*m = append((*m)[:i], (*m)[i+1:]...)
+		i--
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("found conflicting definitions of the same metric:\n%s", strings.Join(conflicts, "\n"))
+	}
+
+	return nil
+}
+
+// logSources writes the number of metrics collected from each source, the component metrics
+// are counted once even though every component lists the metrics of all of them
+func logSources(w io.Writer, endpoints int, scraped, notScraped List) {
+	hardcoded, rules := len(hardcodedMetrics()), 0
+	components := map[string]bool{}
+	for _, m := range notScraped[hardcoded:] {
+		if m.Source == recordingRuleSource {
+			rules++
+		} else {
+			components[m.Name] = true
+		}
+	}
+	fmt.Fprintf(w, "scraped %d metrics from %d endpoints\n", len(scraped), endpoints)
+	fmt.Fprintf(w, "collected %d hardcoded metrics, %d component metrics and %d recording rules\n",
+		hardcoded, len(components), rules)
+}
+
+// recordingRuleSource is the source of the metrics of the recording rules
+const recordingRuleSource = "recording-rule"
+
+// migrationMetricNames are the metrics of the migrations, exposed by virt-handler only while a migration is running
+var migrationMetricNames = []string{
+	domainstats.MigrateVmiDataProcessedMetricName,
+	domainstats.MigrateVmiDataRemainingMetricName,
+	domainstats.MigrateVmiDirtyMemoryRateMetricName,
+	domainstats.MigrateVmiMemoryTransferRateMetricName,
+}
+
+// hardcodedMetrics returns the metrics that can't be collected in process, e.g. because they're only
+// exposed under conditions the fake collectors don't reproduce
+func hardcodedMetrics() List {
+	return List{
+		{
+			Name:        domainstats.MigrateVmiDataProcessedMetricName,
+			Description: "The total Guest OS data processed and migrated to the new VM.",
+			Type:        GaugeType,
+			Stability:   Stable,
+			Labels:      []string{"name", "namespace", "node"},
+			Source:      "virt-handler",
+		},
+		{
+			Name:        domainstats.MigrateVmiDataRemainingMetricName,
+			Description: "The remaining guest OS data to be migrated to the new VM.",
+			Type:        GaugeType,
+			Stability:   Stable,
+			Labels:      []string{"name", "namespace", "node"},
+			Source:      "virt-handler",
+		},
+		{
+			Name:        domainstats.MigrateVmiDirtyMemoryRateMetricName,
+			Description: "The rate of memory being dirty in the Guest OS.",
+			Type:        GaugeType,
+			Stability:   Stable,
+			Labels:      []string{"name", "namespace", "node"},
+			Source:      "virt-handler",
+		},
+		{
+			Name:        domainstats.MigrateVmiMemoryTransferRateMetricName,
+			Description: "The rate at which the memory is being transferred.",
+			Type:        GaugeType,
+			Stability:   Stable,
+			Labels:      []string{"name", "namespace", "node"},
+			Source:      "virt-handler",
+		},
+		{
+			Name:        "kubevirt_vmi_phase_count",
+			Description: "Sum of VMIs per phase and node. `phase` can be one of the following: [`Pending`, `Scheduling`, `Scheduled`, `Running`, `Succeeded`, `Failed`, `Unknown`].",
+			Type:        GaugeType,
+			Stability:   Stable,
+			Labels:      []string{"flavor", "instance_type", "node", "os", "phase", "preference", "workload"},
+			Source:      "virt-controller",
+		},
+		{
+			Name:        "kubevirt_vmi_non_evictable",
+			Description: "Indication for a VirtualMachine that its eviction strategy is set to Live Migration but is not migratable.",
+			Type:        GaugeType,
+			Stability:   Stable,
+			Labels:      []string{"name", "namespace", "node"},
+			Source:      "virt-controller",
+		},
+	}
+}
+
+// checkHardcodedMetrics returns a warning for each hardcoded metric also scraped from the endpoints,
+// which would then be documented twice, and for each migration metric that is neither hardcoded nor scraped
+func checkHardcodedMetrics(hardcoded, scraped List) []string {
+	names := func(metrics List) map[string]bool {
+		set := make(map[string]bool, len(metrics))
+		for _, m := range metrics {
+			set[m.Name] = true
+		}
+		return set
+	}
+	hardcodedNames, scrapedNames := names(hardcoded), names(scraped)
+
+	var warnings []string
+	for _, m := range hardcoded {
+		if scrapedNames[m.Name] {
+			warnings = append(warnings, fmt.Sprintf("%s is hardcoded but also exposed by the endpoint, it should be removed from the hardcoded metrics", m.Name))
+		}
+	}
+	for _, name := range migrationMetricNames {
+		if !hardcodedNames[name] && !scrapedNames[name] {
+			warnings = append(warnings, fmt.Sprintf("%s is neither hardcoded nor exposed by the endpoint, it won't be documented", name))
+		}
+	}
+	return warnings
+}
+
+// checkHardcodedTypes fails if any hardcoded metric also scraped from the endpoints is declared with
+// another type than the scraped one, e.g. a histogram hardcoded as a gauge
+func checkHardcodedTypes(hardcoded, scraped List) error {
+	scrapedTypes := make(map[string]MetricType, len(scraped))
+	for _, m := range scraped {
+		scrapedTypes[m.Name] = m.Type
+	}
+
+	var mismatches []string
+	for _, m := range hardcoded {
+		if actual, ok := scrapedTypes[m.Name]; ok && actual != m.Type {
+			mismatches = append(mismatches, fmt.Sprintf("%s: declared %s, scraped %s", m.Name, m.Type, actual))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("the type of the following hardcoded metrics differs from the scraped one:\n%s", strings.Join(mismatches, "\n"))
+	}
+	return nil
+}
+
+func getMetricsNotIncludeInEndpointByDefault(rulesNamespace string) (List, error) {
+	metrics := hardcodedMetrics()
+
+	components, err := componentMetrics()
+	if err != nil {
+		return nil, err
+	}
+	metrics = append(metrics, components...)
+
+	recordingRules, err := listRecordingRules(rulesNamespace)
+	if err != nil {
+		return nil, err
+	}
+	ruleMetrics, err := recordingRuleMetrics(recordingRules)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(metrics, ruleMetrics...), nil
+}
+
+// componentMetrics returns the metrics registered by the virt-controller, virt-api and virt-operator components,
+// with the component as their source. The registry is shared, so it is cleaned before each component is set up.
+func componentMetrics() (List, error) {
+	var metrics List
+
+	if err := operatormetrics.CleanRegistry(); err != nil {
+		return nil, err
+	}
+	if err := virt_controller.SetupMetrics(nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		return nil, err
+	}
+	metrics, err := appendComponentMetrics(metrics, "virt-controller", virt_controller.ListMetrics())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := operatormetrics.CleanRegistry(); err != nil {
+		return nil, err
+	}
+	if err := virt_api.SetupMetrics(); err != nil {
+		return nil, err
+	}
+	metrics, err = appendComponentMetrics(metrics, "virt-api", virt_api.ListMetrics())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := operatormetrics.CleanRegistry(); err != nil {
+		return nil, err
+	}
+	if err := virt_operator.SetupMetrics(); err != nil {
+		return nil, err
+	}
+	return appendComponentMetrics(metrics, "virt-operator", virt_operator.ListMetrics())
+}
+
+// appendComponentMetrics appends the metrics registered by the component, with the component as their source
+func appendComponentMetrics(metrics List, component string, oms []operatormetrics.Metric) (List, error) {
+	for _, om := range oms {
+		m, err := newMetric(om)
+		if err != nil {
+			return nil, err
+		}
+		m.Source = component
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// listRecordingRules returns the recording rules evaluated against the namespace, replaceable by tests
+var listRecordingRules = func(rulesNamespace string) ([]operatorrules.RecordingRule, error) {
+	if err := rules.SetupRules(rulesNamespace); err != nil {
+		return nil, err
+	}
+	return rules.ListRecordingRules(), nil
+}
+
+// recordingRuleMetrics converts the recording rules, failing if any of them is not documented
+func recordingRuleMetrics(recordingRules []operatorrules.RecordingRule) (List, error) {
+	var metrics List
+	var undocumented []string
+	for _, rule := range recordingRules {
+		if strings.TrimSpace(rule.GetOpts().Help) == "" {
+			undocumented = append(undocumented, rule.GetOpts().Name)
+			continue
+		}
+		mType, err := ParseMetricTypeName(string(rule.GetType()))
+		if err != nil {
+			return nil, fmt.Errorf("recording rule %s: %w", rule.GetOpts().Name, err)
+		}
+		metrics = append(metrics, Metric{
+			Name:        rule.GetOpts().Name,
+			Description: capitalize(rule.GetOpts().Help),
+			Type:        mType,
+			Stability:   optsStability(rule.GetOpts()),
+			Source:      recordingRuleSource,
+			DerivedFrom: exprIdentifiers(rule.Expr.String()),
+		})
+	}
+
+	if len(undocumented) > 0 {
+		return nil, fmt.Errorf("the following recording rules have an empty description: %s", strings.Join(undocumented, ", "))
+	}
+	return metrics, nil
+}
+
+type histogramMetric interface {
+	GetHistogramOpts() prometheus.HistogramOpts
+}
+
+type summaryMetric interface {
+	GetSummaryOpts() prometheus.SummaryOpts
+}
+
+func newMetric(om operatormetrics.Metric) (Metric, error) {
+	mType, err := ParseMetricTypeName(string(om.GetType()))
+	if err != nil {
+		return Metric{}, fmt.Errorf("metric %s: %w", om.GetOpts().Name, err)
+	}
+
+	m := Metric{
+		Name:        om.GetOpts().Name,
+		Description: capitalize(om.GetOpts().Help),
+		Type:        mType,
+		Stability:   optsStability(om.GetOpts()),
+	}
+
+	if histogram, ok := om.(histogramMetric); ok {
+		buckets := histogram.GetHistogramOpts().Buckets
+		if buckets == nil {
+			buckets = prometheus.DefBuckets
+		}
+		m.addBuckets(buckets...)
+		m.addBuckets(math.Inf(1))
+	}
+
+	if summary, ok := om.(summaryMetric); ok {
+		for quantile := range summary.GetSummaryOpts().Objectives {
+			m.addQuantiles(quantile)
+		}
+	}
+
+	return m, nil
+}
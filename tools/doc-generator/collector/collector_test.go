@@ -0,0 +1,1034 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package collector
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+	"github.com/machadovilaca/operator-observability/pkg/operatorrules"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	k6tv1 "kubevirt.io/api/core/v1"
+
+	domainstats "kubevirt.io/kubevirt/pkg/monitoring/domainstats/prometheus"
+	"kubevirt.io/kubevirt/pkg/virt-launcher/virtwrap/stats"
+)
+
+// minimumMetricsCount is the number of metrics KubeVirt is known to expose, catching the accidental
+// removal of a collector. Bump it along with intentionally added or removed metrics.
+const minimumMetricsCount = 88
+
+var _ = Describe("collector", func() {
+	metricNames := func(metrics List) []string {
+		var names []string
+		for _, m := range metrics {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	Context("CollectMetrics", func() {
+		It("should assemble the in-process, hardcoded and recording rule metrics", func() {
+			metrics, err := CollectMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metricNames(metrics)).To(ContainElements(
+				"kubevirt_vmi_memory_available_bytes",
+				"kubevirt_vmi_phase_count",
+				"kubevirt_virt_api_up",
+			))
+		})
+
+		It("should collect at least the known number of metrics", func() {
+			metrics, err := CollectMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metrics.Len()).To(BeNumerically(">=", minimumMetricsCount),
+				"fewer metrics than the %d known ones were collected, update minimumMetricsCount if they were removed intentionally", minimumMetricsCount)
+		})
+
+		It("should not warn about the hardcoded metrics", func() {
+			var warnings strings.Builder
+			_, err := CollectMetrics(Options{Warnings: &warnings})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(warnings.String()).To(BeEmpty())
+		})
+
+		It("should write the number of metrics per source when verbose", func() {
+			var verbose strings.Builder
+			_, err := CollectMetrics(Options{Verbose: &verbose})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verbose.String()).To(MatchRegexp(`^fake domain collector produced [1-9]\d* metric families\n` +
+				`scraped \d+ metrics from 1 endpoints\n` +
+				`collected 6 hardcoded metrics, \d+ component metrics and \d+ recording rules\n$`))
+		})
+
+		It("should only keep the redacted examples of the scraped metrics when requested", func() {
+			metrics, err := CollectMetrics(Options{Samples: true, HighCardinalityLabels: DefaultHighCardinalityLabels})
+			Expect(err).ToNot(HaveOccurred())
+			examples := map[string]*Sample{}
+			for _, m := range metrics {
+				examples[m.Name] = m.Example
+			}
+			Expect(examples["kubevirt_vmi_memory_available_bytes"]).ToNot(BeNil())
+			Expect(examples["kubevirt_vmi_memory_available_bytes"].Labels).To(HaveKeyWithValue("name", redactedValue))
+			Expect(examples["kubevirt_virt_api_up"]).To(BeNil(), "recording rules have no samples")
+
+			metrics, err = CollectMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			for _, m := range metrics {
+				Expect(m.Example).To(BeNil(), m.Name)
+			}
+		})
+
+		It("should be callable more than once", func() {
+			first, err := CollectMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			second, err := CollectMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metricNames(second)).To(Equal(metricNames(first)))
+		})
+	})
+
+	Context("hardcoded metrics", func() {
+		// migrationMetricsExposed returns the names of the metrics virt-handler exposes for a VMI being migrated
+		migrationMetricsExposed := func() []string {
+			ch := make(chan prometheus.Metric, 100)
+			vmStats := &domainstats.VirtualMachineInstanceStats{DomainStats: &stats.DomainStats{
+				Cpu:    &stats.DomainStatsCPU{},
+				Memory: &stats.DomainStatsMemory{},
+				MigrateDomainJobInfo: &stats.DomainJobInfo{
+					DataProcessedSet: true,
+					DataRemainingSet: true,
+					MemDirtyRateSet:  true,
+					MemoryBpsSet:     true,
+				},
+			}}
+			domainstats.NewPrometheusScraper(ch).Report("test", &k6tv1.VirtualMachineInstance{}, vmStats)
+			close(ch)
+
+			fqName := regexp.MustCompile(`fqName: "([^"]+)"`)
+			var names []string
+			for m := range ch {
+				if match := fqName.FindStringSubmatch(m.Desc().String()); match != nil {
+					names = append(names, match[1])
+				}
+			}
+			return names
+		}
+
+		It("should document the component registering the component metrics as their source", func() {
+			// scrape before setting up the component metrics, like CollectMetrics
+			_, err := ScrapeMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			components, err := componentMetrics()
+			Expect(err).ToNot(HaveOccurred())
+
+			sources := map[string]string{}
+			for _, m := range components {
+				sources[m.Name] = m.Source
+			}
+			Expect(sources).To(HaveKeyWithValue("kubevirt_vmi_migrations_in_running_phase", "virt-controller"))
+			Expect(sources).To(HaveKeyWithValue("kubevirt_portforward_active_tunnels", "virt-api"))
+			Expect(sources).To(HaveKeyWithValue("kubevirt_configuration_emulation_enabled", "virt-operator"))
+			for name, source := range sources {
+				Expect(source).ToNot(BeEmpty(), name)
+			}
+		})
+
+		It("should all be registered by a component or exposed by the endpoints", func() {
+			// scrape before setting up the component metrics, like CollectMetrics
+			scraped, err := ScrapeMetrics(Options{})
+			Expect(err).ToNot(HaveOccurred())
+			components, err := componentMetrics()
+			Expect(err).ToNot(HaveOccurred())
+
+			registered := map[string]bool{}
+			for _, name := range append(append(metricNames(components), metricNames(scraped)...), migrationMetricsExposed()...) {
+				registered[name] = true
+			}
+
+			var unregistered []string
+			for _, m := range hardcodedMetrics() {
+				if !registered[m.Name] {
+					unregistered = append(unregistered, m.Name)
+				}
+			}
+			Expect(unregistered).To(BeEmpty(), "the hardcoded metrics are not registered by any collector, were they renamed?")
+		})
+
+		It("should include the metrics exposed during a migration", func() {
+			Expect(migrationMetricsExposed()).To(ConsistOf(migrationMetricNames))
+		})
+	})
+
+	Context("getMetricsNotIncludeInEndpointByDefault", func() {
+		It("should include the recording rules of the given namespace", func() {
+			metrics, err := getMetricsNotIncludeInEndpointByDefault("kubevirt-test")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metricNames(metrics)).To(ContainElements("kubevirt_virt_api_up", "kubevirt_vmi_memory_used_bytes"))
+		})
+
+		Context("with fake recording rules", func() {
+			var rulesNamespace string
+
+			BeforeEach(func() {
+				original := listRecordingRules
+				DeferCleanup(func() { listRecordingRules = original })
+			})
+
+			fakeRecordingRules := func(recordingRules ...operatorrules.RecordingRule) {
+				listRecordingRules = func(namespace string) ([]operatorrules.RecordingRule, error) {
+					rulesNamespace = namespace
+					return recordingRules, nil
+				}
+			}
+
+			It("should convert the rules into metrics, titling their type and keeping their description", func() {
+				fakeRecordingRules(
+					operatorrules.RecordingRule{
+						MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_fake_total", Help: "A fake counter rule."},
+						MetricType:  operatormetrics.CounterType,
+						Expr:        intstr.FromString("sum(kubevirt_vmi_phase_count)"),
+					},
+					operatorrules.RecordingRule{
+						MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_fake", Help: "A fake gauge rule."},
+						MetricType:  operatormetrics.GaugeType,
+						Expr:        intstr.FromString("vector(1)"),
+					},
+				)
+
+				metrics, err := getMetricsNotIncludeInEndpointByDefault("kubevirt-test")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rulesNamespace).To(Equal("kubevirt-test"))
+
+				var ruleMetrics List
+				for _, m := range metrics {
+					if m.Source == recordingRuleSource {
+						ruleMetrics = append(ruleMetrics, m)
+					}
+				}
+				Expect(ruleMetrics).To(HaveLen(2))
+				Expect(ruleMetrics[0].Name).To(Equal("kubevirt_fake_total"))
+				Expect(ruleMetrics[0].Type).To(Equal(CounterType))
+				Expect(ruleMetrics[0].Description).To(Equal("A fake counter rule."))
+				Expect(ruleMetrics[0].DerivedFrom).To(Equal([]string{"kubevirt_vmi_phase_count", "sum"}))
+				Expect(ruleMetrics[1].Name).To(Equal("kubevirt_fake"))
+				Expect(ruleMetrics[1].Type).To(Equal(GaugeType))
+				Expect(ruleMetrics[1].Description).To(Equal("A fake gauge rule."))
+			})
+
+			It("should fail on undocumented rules", func() {
+				fakeRecordingRules(operatorrules.RecordingRule{
+					MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_fake"},
+					MetricType:  operatormetrics.GaugeType,
+				})
+
+				_, err := getMetricsNotIncludeInEndpointByDefault("")
+				Expect(err).To(MatchError("the following recording rules have an empty description: kubevirt_fake"))
+			})
+
+			It("should fail when the rules can't be listed", func() {
+				listRecordingRules = func(string) ([]operatorrules.RecordingRule, error) {
+					return nil, fmt.Errorf("no rules")
+				}
+
+				_, err := getMetricsNotIncludeInEndpointByDefault("")
+				Expect(err).To(MatchError("no rules"))
+			})
+		})
+	})
+
+	Context("checkHardcodedMetrics", func() {
+		It("should warn about hardcoded metrics exposed by the endpoint", func() {
+			hardcoded := hardcodedMetrics()
+			scraped := List{{Name: "kubevirt_vmi_non_evictable"}, {Name: "kubevirt_vmi_memory_available_bytes"}}
+			Expect(checkHardcodedMetrics(hardcoded, scraped)).To(Equal([]string{
+				"kubevirt_vmi_non_evictable is hardcoded but also exposed by the endpoint, it should be removed from the hardcoded metrics",
+			}))
+		})
+
+		It("should warn about migration metrics neither hardcoded nor exposed by the endpoint", func() {
+			var hardcoded List
+			for _, m := range hardcodedMetrics() {
+				if m.Name != migrationMetricNames[0] {
+					hardcoded = append(hardcoded, m)
+				}
+			}
+			scraped := List{{Name: migrationMetricNames[1]}}
+			Expect(checkHardcodedMetrics(hardcoded, scraped)).To(Equal([]string{
+				migrationMetricNames[1] + " is hardcoded but also exposed by the endpoint, it should be removed from the hardcoded metrics",
+				migrationMetricNames[0] + " is neither hardcoded nor exposed by the endpoint, it won't be documented",
+			}))
+		})
+	})
+
+	Context("high cardinality", func() {
+		It("should flag the metrics carrying any of the labels", func() {
+			metrics := List{
+				{Name: "kubevirt_a", Labels: []string{"name", "namespace", "node"}},
+				{Name: "kubevirt_b", Labels: []string{"namespace"}},
+				{Name: "kubevirt_c"},
+			}
+			markHighCardinality(metrics, []string{"node", "name"})
+			Expect(metrics[0].HighCardinalityLabels).To(Equal([]string{"name", "node"}))
+			Expect(metrics[1].HighCardinalityLabels).To(BeEmpty())
+			Expect(metrics[2].HighCardinalityLabels).To(BeEmpty())
+		})
+
+		It("should not flag any metric without labels configured", func() {
+			metrics := List{{Name: "kubevirt_a", Labels: []string{"node"}}}
+			markHighCardinality(metrics, nil)
+			Expect(metrics[0].HighCardinalityLabels).To(BeEmpty())
+		})
+
+		It("should be applied when collecting the metrics", func() {
+			metrics, err := CollectMetrics(Options{HighCardinalityLabels: []string{"node"}})
+			Expect(err).ToNot(HaveOccurred())
+			for _, m := range metrics {
+				if m.Name == "kubevirt_vmi_phase_count" {
+					Expect(m.HighCardinalityLabels).To(Equal([]string{"node"}))
+				}
+			}
+		})
+	})
+
+	Context("checkHardcodedTypes", func() {
+		It("should accept hardcoded metrics scraped with the same type", func() {
+			scraped := List{{Name: "kubevirt_vmi_non_evictable", Type: GaugeType}, {Name: "kubevirt_other", Type: HistogramType}}
+			Expect(checkHardcodedTypes(hardcodedMetrics(), scraped)).To(Succeed())
+		})
+
+		It("should fail on hardcoded metrics scraped with another type", func() {
+			hardcoded := List{
+				{Name: "kubevirt_b_seconds", Type: GaugeType},
+				{Name: "kubevirt_a", Type: HistogramType},
+				{Name: "kubevirt_c", Type: CounterType},
+			}
+			scraped := List{{Name: "kubevirt_a", Type: GaugeType}, {Name: "kubevirt_b_seconds", Type: HistogramType}, {Name: "kubevirt_c", Type: CounterType}}
+			Expect(checkHardcodedTypes(hardcoded, scraped)).To(MatchError("the type of the following hardcoded metrics differs from the scraped one:\n" +
+				"kubevirt_a: declared Histogram, scraped Gauge\n" +
+				"kubevirt_b_seconds: declared Gauge, scraped Histogram"))
+		})
+	})
+
+	Context("overrides", func() {
+		It("should replace and flag the description of the overridden metrics", func() {
+			metrics := List{{Name: "kubevirt_a", Description: "Terse."}, {Name: "kubevirt_b", Description: "The b metric."}}
+			Expect(applyOverrides(metrics, map[string]string{"kubevirt_a": "The enriched a metric."})).To(Succeed())
+			Expect(metrics).To(Equal(List{
+				{Name: "kubevirt_a", Description: "The enriched a metric.", Overridden: true},
+				{Name: "kubevirt_b", Description: "The b metric."},
+			}))
+		})
+
+		It("should fail on overrides of nonexistent metrics", func() {
+			err := applyOverrides(List{{Name: "kubevirt_a"}}, map[string]string{"kubevirt_a": "A.", "kubevirt_c": "C.", "kubevirt_b": "B."})
+			Expect(err).To(MatchError("overridden metrics not found: kubevirt_b, kubevirt_c"))
+		})
+
+		It("should be loaded from a YAML file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "metrics-overrides.yaml")
+			Expect(os.WriteFile(path, []byte("kubevirt_a: \"The enriched a metric: with a colon.\"\n"), 0o600)).To(Succeed())
+			Expect(LoadOverrides(path)).To(Equal(map[string]string{"kubevirt_a": "The enriched a metric: with a colon."}))
+		})
+	})
+
+	Context("source paths", func() {
+		It("should set the path of the sourced metrics only", func() {
+			metrics := List{{Name: "kubevirt_a"}, {Name: "kubevirt_b"}}
+			Expect(applySourcePaths(metrics, map[string]string{"kubevirt_a": "./pkg/monitoring/a.go"})).To(Succeed())
+			Expect(metrics).To(Equal(List{{Name: "kubevirt_a", DefinedIn: "pkg/monitoring/a.go"}, {Name: "kubevirt_b"}}))
+		})
+
+		DescribeTable("should fail on invalid source paths", func(sourcePaths map[string]string, expected string) {
+			Expect(applySourcePaths(List{{Name: "kubevirt_a"}}, sourcePaths)).To(MatchError(expected))
+		},
+			Entry("nonexistent metric", map[string]string{"kubevirt_c": "pkg/c.go"}, "sourced metrics not found: kubevirt_c"),
+			Entry("absolute path", map[string]string{"kubevirt_a": "/pkg/a.go"}, `source path "/pkg/a.go" of kubevirt_a is not relative to the repository root`),
+			Entry("path leaving the repository", map[string]string{"kubevirt_a": "../a.go"}, `source path "../a.go" of kubevirt_a is not relative to the repository root`),
+			Entry("empty path", map[string]string{"kubevirt_a": ""}, `source path "" of kubevirt_a is not relative to the repository root`),
+		)
+
+		It("should be loaded from a YAML file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "metrics-sources.yaml")
+			Expect(os.WriteFile(path, []byte("kubevirt_a: pkg/monitoring/a.go\n"), 0o600)).To(Succeed())
+			Expect(LoadSourcePaths(path)).To(Equal(map[string]string{"kubevirt_a": "pkg/monitoring/a.go"}))
+		})
+	})
+
+	Context("ids", func() {
+		It("should set the id of the identified metrics", func() {
+			metrics := List{{Name: "kubevirt_a"}, {Name: "kubevirt_b"}}
+			Expect(applyIDs(metrics, map[string]string{"kubevirt_a": "a-metric"})).To(Succeed())
+			Expect(metrics).To(Equal(List{{Name: "kubevirt_a", ID: "a-metric"}, {Name: "kubevirt_b"}}))
+		})
+
+		DescribeTable("should fail on ambiguous or invalid ids", func(ids map[string]string, expected string) {
+			Expect(applyIDs(List{{Name: "kubevirt_a"}, {Name: "kubevirt_b"}}, ids)).To(MatchError(expected))
+		},
+			Entry("nonexistent metric", map[string]string{"kubevirt_c": "c-metric"}, "identified metrics not found: kubevirt_c"),
+			Entry("shared id", map[string]string{"kubevirt_a": "metric", "kubevirt_b": "metric"}, "metrics kubevirt_a and kubevirt_b share the id metric"),
+			Entry("name of another metric", map[string]string{"kubevirt_a": "kubevirt_b"}, "id kubevirt_b of kubevirt_a is the name of another metric"),
+			Entry("whitespace", map[string]string{"kubevirt_a": "a metric"}, `id "a metric" of kubevirt_a is not a valid anchor`),
+			Entry("empty", map[string]string{"kubevirt_a": ""}, `id "" of kubevirt_a is not a valid anchor`),
+		)
+
+		It("should be loaded from a YAML file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "metrics-ids.yaml")
+			Expect(os.WriteFile(path, []byte("kubevirt_a: a-metric\n"), 0o600)).To(Succeed())
+			Expect(LoadIDs(path)).To(Equal(map[string]string{"kubevirt_a": "a-metric"}))
+		})
+	})
+
+	Context("recordingRuleMetrics", func() {
+		It("should fail on recording rules with an empty description", func() {
+			recordingRules := []operatorrules.RecordingRule{
+				{
+					MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_documented", Help: "A documented rule."},
+					MetricType:  operatormetrics.GaugeType,
+				},
+				{
+					MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_undocumented"},
+					MetricType:  operatormetrics.GaugeType,
+				},
+			}
+
+			_, err := recordingRuleMetrics(recordingRules)
+			Expect(err).To(MatchError("the following recording rules have an empty description: kubevirt_undocumented"))
+		})
+
+		It("should capitalize the description like the scraped ones", func() {
+			ruleMetrics, err := recordingRuleMetrics([]operatorrules.RecordingRule{{
+				MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_rule", Help: "amount of `used` memory of the VMIs."},
+				MetricType:  operatormetrics.GaugeType,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			scraped := parseMetricDesc("# HELP kubevirt_rule amount of `used` memory of the VMIs.")
+			Expect(ruleMetrics[0].Description).To(Equal("Amount of `used` memory of the VMIs."))
+			Expect(ruleMetrics[0].Description).To(Equal(scraped.Description))
+		})
+	})
+
+	Context("derived from", func() {
+		It("exprIdentifiers should skip quoted strings and numbers", func() {
+			expr := `sum by (namespace) (rate(kubevirt_a_total{pod=~'virt-.*', name="kubevirt_b"}[5m])) or vector(0)`
+			Expect(exprIdentifiers(expr)).To(Equal([]string{"by", "kubevirt_a_total", "name", "namespace", "or", "pod", "rate", "sum", "vector"}))
+		})
+
+		It("should only keep the documented metrics, mapping histogram series to their family", func() {
+			metrics := List{
+				{Name: "kubevirt_a_total", Type: CounterType},
+				{Name: "kubevirt_b_seconds", Type: HistogramType},
+				{Name: "kubevirt_c", Type: GaugeType},
+				{Name: "kubevirt_rule", Type: GaugeType, DerivedFrom: []string{
+					"by", "kubevirt_a_total", "kubevirt_b_seconds_bucket", "kubevirt_b_seconds_count", "kubevirt_c_count", "kubevirt_rule", "sum",
+				}},
+			}
+			resolveDerivedFrom(metrics)
+			Expect(metrics[3].DerivedFrom).To(Equal([]string{"kubevirt_a_total", "kubevirt_b_seconds"}))
+		})
+
+		It("should be set from the expression of the recording rules", func() {
+			ruleMetrics, err := recordingRuleMetrics([]operatorrules.RecordingRule{{
+				MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_rule", Help: "A recording rule."},
+				MetricType:  operatormetrics.GaugeType,
+				Expr:        intstr.FromString("kubevirt_a - kubevirt_b"),
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ruleMetrics[0].DerivedFrom).To(Equal([]string{"kubevirt_a", "kubevirt_b"}))
+		})
+	})
+
+	Context("alerts", func() {
+		It("should document the severity and summary of the alerting rules sorted by name", func() {
+			alerts, err := alertingRules([]promv1.Rule{
+				{
+					Alert:       "KubeVirtB",
+					Expr:        intstr.FromString("kubevirt_b > 0\n"),
+					Labels:      map[string]string{"severity": "critical"},
+					Annotations: map[string]string{"summary": "B is broken."},
+				},
+				{
+					Alert:       "KubeVirtA",
+					Expr:        intstr.FromString("kubevirt_a == 0"),
+					Annotations: map[string]string{"summary": "A is down."},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(Equal([]Alert{
+				{Name: "KubeVirtA", Summary: "A is down.", Expr: "kubevirt_a == 0"},
+				{Name: "KubeVirtB", Severity: "critical", Summary: "B is broken.", Expr: "kubevirt_b > 0"},
+			}))
+		})
+
+		It("should fail on alerting rules with an empty summary", func() {
+			_, err := alertingRules([]promv1.Rule{
+				{Alert: "KubeVirtUndocumented", Expr: intstr.FromString("vector(1)"), Annotations: map[string]string{"summary": " "}},
+				{Alert: "KubeVirtNoAnnotations", Expr: intstr.FromString("vector(1)")},
+			})
+			Expect(err).To(MatchError("the following alerts have an empty summary: KubeVirtNoAnnotations, KubeVirtUndocumented"))
+		})
+
+		It("should collect the alerts shipped with KubeVirt", func() {
+			alerts, err := CollectAlerts("kubevirt")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).ToNot(BeEmpty())
+			for _, alert := range alerts {
+				Expect(alert.Summary).ToNot(BeEmpty())
+			}
+		})
+	})
+
+	It("recording rules and scraped metrics of the same kind should have the same type", func() {
+		ruleMetrics, err := recordingRuleMetrics([]operatorrules.RecordingRule{{
+			MetricsOpts: operatormetrics.MetricOpts{Name: "kubevirt_rule", Help: "A recording rule."},
+			MetricType:  operatormetrics.GaugeType,
+		}})
+		Expect(err).ToNot(HaveOccurred())
+
+		var scraped List
+		exposition := "# HELP kubevirt_scraped A scraped metric.\n# TYPE kubevirt_scraped gauge\nkubevirt_scraped 1\n"
+		Expect(parseVirtMetrics(strings.NewReader(exposition), &scraped, []string{DefaultPrefix})).To(Succeed())
+
+		Expect(ruleMetrics[0].Type).To(Equal(GaugeType))
+		Expect(scraped[0].Type).To(Equal(ruleMetrics[0].Type))
+		Expect(string(scraped[0].Type)).To(Equal("Gauge"))
+	})
+
+	Context("checkPhaseCount", func() {
+		It("should accept the documented phases", func() {
+			metrics, err := getMetricsNotIncludeInEndpointByDefault("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checkPhaseCount(metrics)).To(Succeed())
+		})
+
+		It("should fail when the documented phases diverge from the VMI phases", func() {
+			metrics := List{{
+				Name:        phaseCountMetricName,
+				Description: "Sum of VMIs per phase and node. `phase` can be one of the following: [`Pending`, `Running`, `Paused`].",
+			}}
+			Expect(checkPhaseCount(metrics)).To(MatchError(ContainSubstring("missing: [Scheduling, Scheduled, Succeeded, Failed, Unknown], unknown: [Paused]")))
+		})
+	})
+
+	Context("parseVirtMetrics", func() {
+		It("should unescape line feeds and backslashes in HELP texts", func() {
+			exposition := `# HELP kubevirt_test_metric first line.\nSecond line with a \\ backslash.
+# TYPE kubevirt_test_metric gauge
+kubevirt_test_metric 1
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Description).To(Equal("First line.\nSecond line with a \\ backslash."))
+		})
+
+		It("should only include metrics matching one of the prefixes", func() {
+			exposition := `# HELP kubevirt_test_metric Test metric.
+# TYPE kubevirt_test_metric gauge
+kubevirt_test_metric 1
+# HELP vendor_kubevirt_test_metric Vendor test metric.
+# TYPE vendor_kubevirt_test_metric gauge
+vendor_kubevirt_test_metric 1
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 10
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{"kubevirt_", "vendor_"})).To(Succeed())
+			Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_test_metric", "vendor_kubevirt_test_metric"}))
+		})
+
+		It("should resolve the types of interleaved families", func() {
+			exposition := `# HELP kubevirt_a_total The a metric.
+# HELP kubevirt_b_seconds The b metric.
+# TYPE kubevirt_b_seconds histogram
+kubevirt_b_seconds_bucket{le="+Inf"} 1
+# HELP kubevirt_c The c metric.
+kubevirt_a_total{node="n1"} 1
+# TYPE kubevirt_c gauge
+kubevirt_c 1
+# TYPE kubevirt_a_total counter
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(3))
+			Expect(metrics[0].Name).To(Equal("kubevirt_a_total"))
+			Expect(metrics[0].Type).To(Equal(CounterType))
+			Expect(metrics[0].Labels).To(Equal([]string{"node"}))
+			Expect(metrics[1].Name).To(Equal("kubevirt_b_seconds"))
+			Expect(metrics[1].Type).To(Equal(HistogramType))
+			Expect(metrics[2].Name).To(Equal("kubevirt_c"))
+			Expect(metrics[2].Type).To(Equal(GaugeType))
+		})
+
+		It("should document the bucket boundaries of histograms", func() {
+			exposition := `# HELP kubevirt_test_duration_seconds Test duration.
+# TYPE kubevirt_test_duration_seconds histogram
+kubevirt_test_duration_seconds_bucket{phase="Running",le="10"} 1
+kubevirt_test_duration_seconds_bucket{phase="Running",le="0.5"} 0
+kubevirt_test_duration_seconds_bucket{phase="Running",le="+Inf"} 1
+kubevirt_test_duration_seconds_sum{phase="Running"} 3
+kubevirt_test_duration_seconds_count{phase="Running"} 1
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Labels).To(Equal([]string{"phase"}))
+			Expect(metrics[0].Buckets).To(Equal([]float64{0.5, 10, math.Inf(1)}))
+		})
+
+		It("should fold the histogram sub-series into the histogram", func() {
+			exposition := `# HELP kubevirt_test_duration_seconds Test histogram.
+# TYPE kubevirt_test_duration_seconds histogram
+# HELP kubevirt_test_duration_seconds_bucket Test histogram buckets.
+# TYPE kubevirt_test_duration_seconds_bucket counter
+kubevirt_test_duration_seconds_bucket{phase="Running",le="1"} 1
+kubevirt_test_duration_seconds_bucket{phase="Running",le="+Inf"} 2
+# HELP kubevirt_test_duration_seconds_sum Test histogram sum.
+# TYPE kubevirt_test_duration_seconds_sum counter
+kubevirt_test_duration_seconds_sum{phase="Running"} 3
+# HELP kubevirt_test_duration_seconds_count Test histogram count.
+# TYPE kubevirt_test_duration_seconds_count counter
+kubevirt_test_duration_seconds_count{phase="Running"} 2
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Name).To(Equal("kubevirt_test_duration_seconds"))
+			Expect(metrics[0].Type).To(Equal(HistogramType))
+			Expect(metrics[0].Labels).To(Equal([]string{"phase"}))
+			Expect(metrics[0].Buckets).To(Equal([]float64{1, math.Inf(1)}))
+		})
+
+		It("should keep the first sample of each family as its example, the _sum one for histograms", func() {
+			exposition := `# HELP kubevirt_test_gauge Test gauge.
+# TYPE kubevirt_test_gauge gauge
+kubevirt_test_gauge{node="node01",phase="running"} 0.42 1700000000000
+kubevirt_test_gauge{node="node02",phase="pending"} 3
+# HELP kubevirt_test_seconds Test histogram.
+# TYPE kubevirt_test_seconds histogram
+kubevirt_test_seconds_bucket{le="1"} 2
+kubevirt_test_seconds_bucket{le="+Inf"} 3
+kubevirt_test_seconds_sum 4.5
+kubevirt_test_seconds_count 3
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(2))
+			Expect(metrics[0].Example).To(Equal(&Sample{Name: "kubevirt_test_gauge", Labels: map[string]string{"node": "node01", "phase": "running"}, Value: "0.42"}))
+			Expect(metrics[1].Example).To(Equal(&Sample{Name: "kubevirt_test_seconds_sum", Value: "4.5"}))
+		})
+
+		It("should fold the summary sub-series into the summary and document its quantiles", func() {
+			exposition := `# HELP kubevirt_test_latency_seconds Test summary.
+# TYPE kubevirt_test_latency_seconds summary
+kubevirt_test_latency_seconds{verb="GET",quantile="0.99"} 0.3
+kubevirt_test_latency_seconds{verb="GET",quantile="0.5"} 0.1
+kubevirt_test_latency_seconds{verb="GET",quantile="0.9"} 0.2
+kubevirt_test_latency_seconds_sum{verb="GET"} 12
+kubevirt_test_latency_seconds_count{verb="GET"} 100
+# HELP kubevirt_test_latency_seconds_count Test summary count.
+# TYPE kubevirt_test_latency_seconds_count counter
+kubevirt_test_latency_seconds_count{verb="PUT"} 1
+`
+			var metrics List
+			Expect(parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Name).To(Equal("kubevirt_test_latency_seconds"))
+			Expect(metrics[0].Type).To(Equal(SummaryType))
+			Expect(metrics[0].Labels).To(Equal([]string{"verb"}))
+			Expect(metrics[0].Quantiles).To(Equal([]float64{0.5, 0.9, 0.99}))
+			Expect(metrics[0].Buckets).To(BeEmpty())
+		})
+
+		It("should fail on unknown metric types", func() {
+			exposition := "# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gaugehistogram\nkubevirt_test 1\n"
+			var metrics List
+			err := parseVirtMetrics(strings.NewReader(exposition), &metrics, []string{DefaultPrefix})
+			Expect(err).To(MatchError(`metric kubevirt_test: unknown metric type "gaugehistogram"`))
+		})
+	})
+
+	It("should document the objectives of the component summaries as quantiles", func() {
+		m, err := newMetric(operatormetrics.NewSummary(
+			operatormetrics.MetricOpts{Name: "kubevirt_test_summary_seconds", Help: "Test summary."},
+			prometheus.SummaryOpts{Objectives: map[float64]float64{0.9: 0.01, 0.5: 0.05}},
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Type).To(Equal(SummaryType))
+		Expect(m.Quantiles).To(Equal([]float64{0.5, 0.9}))
+	})
+
+	It("should capitalize the description of the component metrics like the scraped ones", func() {
+		m, err := newMetric(operatormetrics.NewGauge(operatormetrics.MetricOpts{Name: "kubevirt_test_gauge", Help: "number of VMIs."}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Description).To(Equal("Number of VMIs."))
+	})
+
+	DescribeTable("looksLikeIdentifier", func(value string, expected bool) {
+		Expect(looksLikeIdentifier(value)).To(Equal(expected))
+	},
+		Entry("UUID", "6a1a2b3c-4d5e-6f70-8192-a3b4c5d6e7f8", true),
+		Entry("hash", "5f9c4ab08cac7457", true),
+		Entry("IPv4 address", "10.244.0.12", true),
+		Entry("IPv6 address", "fd00:10:244::c", true),
+		Entry("generated pod name", "virt-launcher-testvmi-x7k2p", true),
+		Entry("phase", "running", false),
+		Entry("short hex word", "cafe", false),
+		Entry("dashed word", "live-migration", false),
+		Entry("version", "v1", false),
+	)
+
+	It("redactExamples should redact the high cardinality labels and the identifiers", func() {
+		example := &Sample{Name: "kubevirt_a", Labels: map[string]string{
+			"name":  "testvmi",
+			"uid":   "6a1a2b3c-4d5e-6f70-8192-a3b4c5d6e7f8",
+			"phase": "running",
+		}, Value: "1"}
+		metrics := List{{Name: "kubevirt_a", HighCardinalityLabels: []string{"name"}, Example: example}}
+		redactExamples(metrics)
+		Expect(metrics[0].Example.String()).To(Equal(`kubevirt_a{name="<redacted>",phase="running",uid="<redacted>"} 1`))
+		Expect(example.Labels).To(HaveKeyWithValue("name", "testvmi"), "the scraped sample should be left untouched")
+	})
+
+	It("Sample.String should escape the label values", func() {
+		Expect(Sample{Name: "kubevirt_a", Value: "2"}.String()).To(Equal("kubevirt_a 2"))
+		Expect(Sample{Name: "kubevirt_a", Labels: map[string]string{"reason": `a "b"\c`}, Value: "2"}.String()).To(Equal(`kubevirt_a{reason="a \"b\"\\c"} 2`))
+	})
+
+	It("removeDuplicates should keep the source of the merged metrics", func() {
+		metrics := List{
+			{Name: "kubevirt_a", Type: GaugeType, Stability: Stable, Source: "virt-controller"},
+			{Name: "kubevirt_a", Type: GaugeType, Stability: Stable},
+		}
+		Expect(metrics.removeDuplicates()).To(Succeed())
+		Expect(metrics).To(HaveLen(1))
+		Expect(metrics[0].Source).To(Equal("virt-controller"))
+	})
+
+	DescribeTable("parseMetricDesc should capitalize the description without changing acronyms", func(help, expected string) {
+		Expect(parseMetricDesc("# HELP kubevirt_test " + help).Description).To(Equal(expected))
+	},
+		Entry("lowercase sentence", "vmi data processed.", "Vmi data processed."),
+		Entry("leading acronym", "CPU usage of the VMI.", "CPU usage of the VMI."),
+		Entry("hyphenated first word", "non-running VMIs.", "Non-running VMIs."),
+	)
+
+	It("parseMetricDesc should parse the deprecation annotation", func() {
+		Expect(parseMetricDesc("# HELP kubevirt_test [DEPRECATED] Test metric.").Deprecated()).To(BeTrue())
+	})
+
+	DescribeTable("parseMetricDesc should parse the deprecation timeline annotation", func(help string, expected Metric) {
+		expected.Name = "kubevirt_test"
+		Expect(parseMetricDesc("# HELP kubevirt_test " + help)).To(Equal(expected))
+	},
+		Entry("without the annotation", "Test metric.",
+			Metric{Description: "Test metric.", Stability: Stable}),
+		Entry("with both versions", "Test metric. [deprecated:v1.2 removed:v1.4]",
+			Metric{Description: "Test metric.", Stability: Deprecated, DeprecatedInVersion: "v1.2", RemovedInVersion: "v1.4"}),
+		Entry("with the removal version only, leading", "[removed:v1.4] test metric.",
+			Metric{Description: "Test metric.", Stability: Deprecated, RemovedInVersion: "v1.4"}),
+		Entry("after the stability annotation", "[DEPRECATED] Test metric [deprecated:v1.2] in bytes.",
+			Metric{Description: "Test metric in bytes.", Stability: Deprecated, DeprecatedInVersion: "v1.2"}),
+		Entry("with unrecognized tokens", "Test metric [deprecated:v1.2 since:v1.1].",
+			Metric{Description: "Test metric [deprecated:v1.2 since:v1.1].", Stability: Stable}),
+		Entry("with other bracketed text", "Sum of VMIs per [phase].",
+			Metric{Description: "Sum of VMIs per [phase].", Stability: Stable}),
+	)
+
+	It("parseMetricDesc should return an empty description for HELP lines without one", func() {
+		Expect(parseMetricDesc("# HELP kubevirt_foo")).To(Equal(Metric{Name: "kubevirt_foo", Stability: Stable}))
+
+		var metrics List
+		Expect(parseVirtMetrics(strings.NewReader("# HELP kubevirt_foo\n# TYPE kubevirt_foo gauge\nkubevirt_foo 1\n"), &metrics, []string{DefaultPrefix})).To(Succeed())
+		Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_foo"}))
+	})
+
+	DescribeTable("parseSample", func(line, expectedName string, expectedLabels map[string]string, expectedValue string) {
+		name, labels, value, err := parseSample(line)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal(expectedName))
+		Expect(labels).To(Equal(expectedLabels))
+		Expect(value).To(Equal(expectedValue))
+	},
+		Entry("without labels", "kubevirt_a 1", "kubevirt_a", nil, "1"),
+		Entry("with labels", `kubevirt_a{node="node01",phase="running"} 2`, "kubevirt_a", map[string]string{"node": "node01", "phase": "running"}, "2"),
+		Entry("with a timestamp", "kubevirt_a 3 1700000000000", "kubevirt_a", nil, "3"),
+		Entry("with an escaped label value", `kubevirt_a{reason="a \"b\""} 4`, "kubevirt_a", map[string]string{"reason": `a "b"`}, "4"),
+	)
+
+	DescribeTable("parseSample should fail on invalid samples", func(line string) {
+		_, _, _, err := parseSample(line)
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("whitespace only", "   "),
+		Entry("unterminated label value", `kubevirt_a{node="node01} 1`),
+		Entry("label without a value", `kubevirt_a{node} 1`),
+	)
+
+	It("parseVirtMetrics should skip the lines holding only whitespace", func() {
+		var metrics List
+		Expect(parseVirtMetrics(strings.NewReader("# HELP kubevirt_foo Foo.\n# TYPE kubevirt_foo gauge\n \t\nkubevirt_foo 1\n"), &metrics, []string{DefaultPrefix})).To(Succeed())
+		Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_foo"}))
+	})
+
+	DescribeTable("ParseMetricTypeName should normalize the metric types", func(name string, expected MetricType) {
+		Expect(ParseMetricTypeName(name)).To(Equal(expected))
+	},
+		Entry("exposition counter", "counter", CounterType),
+		Entry("exposition untyped", "untyped", UntypedType),
+		Entry("operatormetrics gauge", "Gauge", GaugeType),
+		Entry("operatormetrics histogram vector", "HistogramVec", HistogramType),
+		Entry("operatormetrics summary vector", "SummaryVec", SummaryType),
+	)
+
+	Context("scrapeEndpoint", func() {
+		It("should return the exposed metrics", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, "# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test 1\n")
+			}))
+			defer server.Close()
+
+			exposition, err := scrapeEndpoint(context.Background(), server.URL, DefaultEndpointTimeout)
+			Expect(err).ToNot(HaveOccurred())
+
+			var metrics List
+			Expect(parseVirtMetrics(exposition, &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_test"}))
+		})
+
+		It("should decompress a gzip encoded response", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Accept-Encoding")).To(Equal("gzip"))
+				w.Header().Set("Content-Encoding", "gzip")
+				gz := gzip.NewWriter(w)
+				defer gz.Close()
+				fmt.Fprint(gz, "# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test 1\n")
+			}))
+			defer server.Close()
+
+			exposition, err := scrapeEndpoint(context.Background(), server.URL, DefaultEndpointTimeout)
+			Expect(err).ToNot(HaveOccurred())
+
+			var metrics List
+			Expect(parseVirtMetrics(exposition, &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_test"}))
+		})
+
+		It("should fail on a response which claims to be gzip encoded but isn't", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				fmt.Fprint(w, "# HELP kubevirt_test Test metric.\n")
+			}))
+			defer server.Close()
+
+			_, err := scrapeEndpoint(context.Background(), server.URL, DefaultEndpointTimeout)
+			Expect(err).To(MatchError(ContainSubstring("failed to decompress the response")))
+		})
+
+		It("should fail on a non-200 response", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			_, err := scrapeEndpoint(context.Background(), server.URL, DefaultEndpointTimeout)
+			Expect(err).To(MatchError("got HTTP status code of 503 from " + server.URL))
+		})
+
+		It("should include the response body of a non-200 response", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "error gathering metrics: collector failed", http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			_, err := scrapeEndpoint(context.Background(), server.URL, DefaultEndpointTimeout)
+			Expect(err).To(MatchError("got HTTP status code of 500 from " + server.URL + ": error gathering metrics: collector failed"))
+		})
+
+		It("should fail when the endpoint doesn't answer in time", func() {
+			done := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				select {
+				case <-done:
+				case <-r.Context().Done():
+				}
+			}))
+			defer server.Close()
+			defer close(done)
+
+			_, err := scrapeEndpoint(context.Background(), server.URL, 10*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("timed out scraping " + server.URL + " after")))
+		})
+
+		Context("retries", func() {
+			BeforeEach(func() {
+				original := retryBackoff
+				retryBackoff = time.Millisecond
+				DeferCleanup(func() { retryBackoff = original })
+			})
+
+			// failingServer answers with the status code until the given number of requests failed
+			failingServer := func(code int, failures int32, requests *int32) *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					if atomic.AddInt32(requests, 1) <= failures {
+						w.WriteHeader(code)
+						return
+					}
+					fmt.Fprint(w, "# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test 1\n")
+				}))
+			}
+
+			It("should retry on server errors, logging each retry", func() {
+				var requests int32
+				server := failingServer(http.StatusServiceUnavailable, 2, &requests)
+				defer server.Close()
+
+				var verbose strings.Builder
+				_, err := scrapeEndpointRetrying(context.Background(), server.URL, DefaultEndpointTimeout, 3, &verbose)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requests).To(BeEquivalentTo(3))
+				Expect(verbose.String()).To(Equal(
+					"retrying to scrape " + server.URL + " in 1ms (1/3): got HTTP status code of 503 from " + server.URL + "\n" +
+						"retrying to scrape " + server.URL + " in 2ms (2/3): got HTTP status code of 503 from " + server.URL + "\n"))
+			})
+
+			It("should give up after the retries", func() {
+				var requests int32
+				server := failingServer(http.StatusInternalServerError, 10, &requests)
+				defer server.Close()
+
+				_, err := scrapeEndpointRetrying(context.Background(), server.URL, DefaultEndpointTimeout, 2, nil)
+				Expect(err).To(MatchError("got HTTP status code of 500 from " + server.URL))
+				Expect(requests).To(BeEquivalentTo(3))
+			})
+
+			It("should not retry on client errors", func() {
+				var requests int32
+				server := failingServer(http.StatusNotFound, 1, &requests)
+				defer server.Close()
+
+				_, err := scrapeEndpointRetrying(context.Background(), server.URL, DefaultEndpointTimeout, 3, nil)
+				Expect(err).To(MatchError("got HTTP status code of 404 from " + server.URL))
+				Expect(requests).To(BeEquivalentTo(1))
+			})
+
+			It("should retry on connection errors", func() {
+				server := httptest.NewServer(http.NotFoundHandler())
+				url := server.URL
+				server.Close()
+
+				var verbose strings.Builder
+				_, err := scrapeEndpointRetrying(context.Background(), url, DefaultEndpointTimeout, 1, &verbose)
+				Expect(err).To(HaveOccurred())
+				Expect(verbose.String()).To(HavePrefix("retrying to scrape " + url + " in 1ms (1/1): "))
+			})
+		})
+	})
+
+	Context("multiple endpoints", func() {
+		serve := func(exposition string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, exposition)
+			}))
+		}
+
+		It("should merge the metrics of all the endpoints", func() {
+			first := serve("# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test{node=\"a\"} 1\n")
+			defer first.Close()
+			second := serve("# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test{pod=\"b\"} 1\n" +
+				"# HELP kubevirt_other Other metric.\n# TYPE kubevirt_other counter\nkubevirt_other 1\n")
+			defer second.Close()
+
+			var metrics List
+			expositions, err := scrapeEndpoints([]string{first.URL, second.URL}, DefaultEndpointTimeout, 0, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parseExpositions(expositions, &metrics, []string{DefaultPrefix})).To(Succeed())
+			Expect(metrics).To(HaveLen(2))
+			Expect(metrics[1].Name).To(Equal("kubevirt_test"))
+			Expect(metrics[1].Labels).To(Equal([]string{"node", "pod"}))
+		})
+
+		It("should fail on conflicting definitions across endpoints", func() {
+			first := serve("# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test 1\n")
+			defer first.Close()
+			second := serve("# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test counter\nkubevirt_test 1\n")
+			defer second.Close()
+
+			var metrics List
+			expositions, err := scrapeEndpoints([]string{first.URL, second.URL}, DefaultEndpointTimeout, 0, nil)
+			Expect(err).ToNot(HaveOccurred())
+			err = parseExpositions(expositions, &metrics, []string{DefaultPrefix})
+			Expect(err).To(MatchError(ContainSubstring("found conflicting definitions of the same metric")))
+		})
+
+		It("ScrapeMetrics should only return the scraped metrics", func() {
+			server := serve("# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test 1\n")
+			defer server.Close()
+
+			metrics, err := ScrapeMetrics(Options{Endpoints: []string{server.URL}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_test"}))
+		})
+
+		Context("strict prefix", func() {
+			const exposition = "# HELP kubevirt_test Test metric.\n# TYPE kubevirt_test gauge\nkubevirt_test 1\n" +
+				"# HELP go_goroutines Number of goroutines.\n# TYPE go_goroutines gauge\ngo_goroutines 1\n" +
+				"# HELP kubvirt_typo Misnamed metric.\n# TYPE kubvirt_typo gauge\nkubvirt_typo 1\n"
+
+			It("should silently leave out the unprefixed metrics by default", func() {
+				server := serve(exposition)
+				defer server.Close()
+
+				metrics, err := ScrapeMetrics(Options{Endpoints: []string{server.URL}})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metricNames(metrics)).To(Equal([]string{"kubevirt_test"}))
+			})
+
+			It("should fail on the unprefixed metrics, except the runtime ones", func() {
+				first := serve(exposition)
+				defer first.Close()
+				second := serve("# HELP kubvirt_typo Misnamed metric.\n# TYPE kubvirt_typo gauge\nkubvirt_typo 1\n" +
+					"# HELP virt_other Other metric.\n# TYPE virt_other counter\nvirt_other 1\n")
+				defer second.Close()
+
+				_, err := ScrapeMetrics(Options{Endpoints: []string{first.URL, second.URL}, StrictPrefix: true})
+				Expect(err).To(MatchError("the following metrics don't start with any of the prefixes kubevirt_: kubvirt_typo, virt_other"))
+			})
+		})
+	})
+})
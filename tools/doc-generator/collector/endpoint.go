@@ -0,0 +1,269 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	domainstats "kubevirt.io/kubevirt/pkg/monitoring/domainstats/prometheus"
+)
+
+// DefaultEndpointTimeout is the default timeout of scraping a live metrics endpoint
+const DefaultEndpointTimeout = 30 * time.Second
+
+// DefaultEndpointRetries is the default number of times scraping a live metrics endpoint is retried
+const DefaultEndpointRetries = 3
+
+// retryBackoff is the delay before the first retry of a failed scrape, doubled on each retry
+var retryBackoff = 500 * time.Millisecond
+
+// retriableError is a scrape failure which may not persist, e.g. while the component is booting
+type retriableError struct {
+	error
+}
+
+func (e retriableError) Unwrap() error {
+	return e.error
+}
+
+// exposition is the text exposition scraped from a metrics endpoint
+type exposition struct {
+	endpoint string
+	body     io.Reader
+}
+
+// scrapeEndpoints collects the metrics exposed by each endpoint, or by the in-process
+// fake collectors when there are none. The retries, or the number of metric families
+// of each fake collector, are written to verbose unless nil
+func scrapeEndpoints(endpoints []string, timeout time.Duration, retries int, verbose io.Writer) ([]exposition, error) {
+	if len(endpoints) == 0 {
+		body, err := scrapeInProcess()
+		if err != nil {
+			return nil, err
+		}
+		if verbose != nil {
+			logFakeCollectors(verbose)
+		}
+		return []exposition{{endpoint: "/metrics", body: body}}, nil
+	}
+
+	expositions := make([]exposition, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		body, err := scrapeEndpointRetrying(context.Background(), endpoint, timeout, retries, verbose)
+		if err != nil {
+			return nil, err
+		}
+		expositions = append(expositions, exposition{endpoint: endpoint, body: body})
+	}
+	return expositions, nil
+}
+
+// parseExpositions merges the metrics of all the expositions into the list,
+// failing on conflicting definitions of the same metric across endpoints
+func parseExpositions(expositions []exposition, metrics *List, prefixes []string) error {
+	return parseExpositionsSkipping(expositions, metrics, prefixes, nil)
+}
+
+func parseExpositionsSkipping(expositions []exposition, metrics *List, prefixes []string, skipped func(name string)) error {
+	for _, e := range expositions {
+		if err := parseVirtMetricsSkipping(e.body, metrics, prefixes, skipped); err != nil {
+			return fmt.Errorf("failed to parse the metrics of %s: %v", e.endpoint, err)
+		}
+	}
+	return nil
+}
+
+// runtimeMetricPrefixes are the prefixes of the metrics of the process and Go runtime collectors
+// of the Prometheus client, which every endpoint exposes regardless of the prefixes
+var runtimeMetricPrefixes = []string{"go_", "process_", "promhttp_"}
+
+// unprefixedError fails listing the skipped metrics, except the runtime ones, which don't start with any of the prefixes
+func unprefixedError(skipped []string, prefixes []string) error {
+	var unprefixed []string
+	seen := map[string]bool{}
+	for _, name := range skipped {
+		if !HasAnyPrefix(name, runtimeMetricPrefixes) && !seen[name] {
+			seen[name] = true
+			unprefixed = append(unprefixed, name)
+		}
+	}
+	if len(unprefixed) == 0 {
+		return nil
+	}
+
+	sort.Strings(unprefixed)
+	return fmt.Errorf("the following metrics don't start with any of the prefixes %s: %s",
+		strings.Join(prefixes, ", "), strings.Join(unprefixed, ", "))
+}
+
+var (
+	scrapeInProcessOnce sync.Once
+	inProcessExposition []byte
+	inProcessFamilies   map[string]int
+	inProcessErr        error
+)
+
+// scrapeInProcess collects the metrics exposed by the domain stats handler fed by the fake collectors,
+// the handler is only scraped once as the component metrics set up afterwards can't be collected in process
+func scrapeInProcess() (io.Reader, error) {
+	scrapeInProcessOnce.Do(func() {
+		inProcessExposition, inProcessFamilies, inProcessErr = scrapeFakeCollectors()
+	})
+	if inProcessErr != nil {
+		return nil, inProcessErr
+	}
+	return bytes.NewReader(inProcessExposition), nil
+}
+
+// scrapeFakeCollectors registers the fake collectors and scrapes the domain stats handler, also
+// returning the number of metric families each fake collector produced
+func scrapeFakeCollectors() ([]byte, map[string]int, error) {
+	handler := domainstats.Handler(1)
+
+	families := make(map[string]int, len(fakeCollectors))
+	for _, fc := range fakeCollectors {
+		count, err := countMetricFamilies(fc.collector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to collect the fake %s collector: %v", fc.name, err)
+		}
+		families[fc.name] = count
+		prometheus.MustRegister(fc.collector)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if err := statusError(recorder.Code, "/metrics", recorder.Body.Bytes()); err != nil {
+		return nil, nil, err
+	}
+	return recorder.Body.Bytes(), families, nil
+}
+
+// countMetricFamilies returns the number of metric families the collector produces on its own,
+// gathering it from a registry of its own not to disturb the default one
+func countMetricFamilies(c prometheus.Collector) (int, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(c); err != nil {
+		return 0, err
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		return 0, err
+	}
+	return len(families), nil
+}
+
+// logFakeCollectors writes the number of metric families produced by each fake collector
+func logFakeCollectors(w io.Writer) {
+	for _, fc := range fakeCollectors {
+		fmt.Fprintf(w, "fake %s collector produced %d metric families\n", fc.name, inProcessFamilies[fc.name])
+	}
+}
+
+// scrapeEndpointRetrying is scrapeEndpoint retrying up to the given number of times, with an exponential
+// backoff, on connection errors and server errors. Client errors are not retried
+func scrapeEndpointRetrying(ctx context.Context, url string, timeout time.Duration, retries int, verbose io.Writer) (io.Reader, error) {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		body, err := scrapeEndpoint(ctx, url, timeout)
+		var retriable retriableError
+		if err == nil || attempt >= retries || !errors.As(err, &retriable) {
+			return body, err
+		}
+
+		if verbose != nil {
+			fmt.Fprintf(verbose, "retrying to scrape %s in %s (%d/%d): %v\n", url, backoff, attempt+1, retries, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// scrapeEndpoint collects the metrics exposed by a live metrics endpoint, giving up after the timeout
+func scrapeEndpoint(ctx context.Context, url string, timeout time.Duration) (io.Reader, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// setting the header explicitly disables the transparent decompression of the transport,
+	// the body is decompressed by readBody instead
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, retriableError{scrapeFailure(ctx, url, start, err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, scrapeFailure(ctx, url, start, fmt.Errorf("failed to read the metrics of %s: %v", url, err))
+	}
+
+	if err := statusError(resp.StatusCode, url, body); err != nil {
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, retriableError{err}
+		}
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+// readBody reads the response body, decompressing it if it is gzip encoded
+func readBody(resp *http.Response) ([]byte, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(resp.Body)
+	}
+
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the response: %v", err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// statusError returns an error for a non-OK response, including the response body as it
+// usually describes the failure, e.g. the errors of the collectors gathered by the handler
+func statusError(code int, source string, body []byte) error {
+	if code == http.StatusOK {
+		return nil
+	}
+
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Errorf("got HTTP status code of %d from %s: %s", code, source, message)
+	}
+	return fmt.Errorf("got HTTP status code of %d from %s", code, source)
+}
+
+// scrapeFailure returns the error, replaced by one naming the elapsed time when the scrape timed out
+func scrapeFailure(ctx context.Context, url string, start time.Time, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out scraping %s after %s", url, time.Since(start).Round(time.Millisecond))
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Sample is a representative sample of a metric, e.g. `kubevirt_vmi_phase_count{phase="running"} 1`
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  string
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// String formats the sample as an exposition line, the labels sorted by key
+func (s Sample) String() string {
+	if len(s.Labels) == 0 {
+		return s.Name + " " + s.Value
+	}
+
+	keys := make([]string, 0, len(s.Labels))
+	for key := range s.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+`="`+labelValueEscaper.Replace(s.Labels[key])+`"`)
+	}
+	return s.Name + "{" + strings.Join(pairs, ",") + "} " + s.Value
+}
+
+// redactedValue replaces the label values of the examples which could leak cluster specifics
+const redactedValue = "<redacted>"
+
+var (
+	uuidValue = regexp.MustCompile(`^[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}$`)
+	hashValue = regexp.MustCompile(`^[0-9a-f]*[0-9][0-9a-f]*$`)
+	// generatedNameSuffix matches the random suffix of generated Kubernetes names, e.g. virt-launcher-testvmi-x7k2p
+	generatedNameSuffix = regexp.MustCompile(`-[a-z0-9]*[0-9][a-z0-9]*$`)
+)
+
+// looksLikeIdentifier reports whether the label value is likely specific to a cluster, e.g. a UUID,
+// a hash, an IP address or a generated name
+func looksLikeIdentifier(value string) bool {
+	switch {
+	case uuidValue.MatchString(value), net.ParseIP(value) != nil:
+		return true
+	case len(value) >= 8 && hashValue.MatchString(value):
+		return true
+	}
+	suffix := generatedNameSuffix.FindString(value)
+	return len(suffix) >= 6 && len(suffix) <= 11
+}
+
+// addExample keeps the first sample of the metric family as its example, the _sum one for histograms
+func (m *Metric) addExample(sampleName string, labels map[string]string, value string) {
+	if m.Example != nil || (sampleName != m.Name && sampleName != m.Name+"_sum") {
+		return
+	}
+	m.Example = &Sample{Name: sampleName, Labels: labels, Value: value}
+}
+
+// redactExamples replaces the values of the high cardinality labels of the examples, and of the labels
+// looking like identifiers, not to leak the specifics of the scraped cluster into the documentation
+func redactExamples(metrics List) {
+	for i := range metrics {
+		example := metrics[i].Example
+		if example == nil {
+			continue
+		}
+
+		redacted := make(map[string]string, len(example.Labels))
+		for key, value := range example.Labels {
+			if looksLikeIdentifier(value) || slices.Contains(metrics[i].HighCardinalityLabels, key) {
+				value = redactedValue
+			}
+			redacted[key] = value
+		}
+		metrics[i].Example = &Sample{Name: example.Name, Labels: redacted, Value: example.Value}
+	}
+}
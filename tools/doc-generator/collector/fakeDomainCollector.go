@@ -1,4 +1,4 @@
-package main
+package collector
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
@@ -86,6 +86,13 @@ func (*fakeDomainIdentifier) GetUUIDString() (string, error) {
 	return "uuid", nil
 }
 
-func RegisterFakeDomainCollector() {
-	prometheus.MustRegister(fakeDomainCollector{})
+// fakeCollector is a named collector standing in for a component which can't run in process
+type fakeCollector struct {
+	name      string
+	collector prometheus.Collector
+}
+
+// fakeCollectors are the collectors registered to be scraped in process
+var fakeCollectors = []fakeCollector{
+	{name: "domain", collector: fakeDomainCollector{}},
 }
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+)
+
+// exprIdentifiers returns the sorted set of the identifiers in a PromQL expression which could be
+// metric names, skipping quoted strings. Keywords, functions and label names are returned as well;
+// they fall out when the identifiers are resolved against the known metrics.
+func exprIdentifiers(expr string) []string {
+	seen := map[string]bool{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			i = skipQuoted(expr, i)
+		case isIdentifierStart(c):
+			start := i
+			for i < len(expr) && isIdentifierPart(expr[i]) {
+				i++
+			}
+			seen[expr[start:i]] = true
+		case isDigit(c):
+			// numbers and durations, e.g. 5m, must not yield identifiers
+			for i < len(expr) && isIdentifierPart(expr[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+
+	var identifiers []string
+	for identifier := range seen {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+	return identifiers
+}
+
+// skipQuoted returns the index following the string starting at i, backslash escapes don't apply to raw strings
+func skipQuoted(expr string, i int) int {
+	quote := expr[i]
+	for i++; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\\' && quote != '`':
+			i++
+		case expr[i] == quote:
+			return i + 1
+		}
+	}
+	return i
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// histogramSeriesSuffixes are the suffixes of the series of histogram and summary families
+var histogramSeriesSuffixes = []string{"_bucket", "_sum", "_count"}
+
+// resolveDerivedFrom narrows the identifiers referenced by the expressions of the recording rules
+// down to the documented metrics, mapping the series of histograms and summaries to their family
+func resolveDerivedFrom(metrics List) {
+	known := make(map[string]MetricType, len(metrics))
+	for _, m := range metrics {
+		known[m.Name] = m.Type
+	}
+
+	for i := range metrics {
+		if len(metrics[i].DerivedFrom) == 0 {
+			continue
+		}
+
+		var derivedFrom []string
+		for _, identifier := range metrics[i].DerivedFrom {
+			if name, ok := resolveMetricName(known, identifier); ok && name != metrics[i].Name {
+				derivedFrom = append(derivedFrom, name)
+			}
+		}
+		sort.Strings(derivedFrom)
+		metrics[i].DerivedFrom = dedupSorted(derivedFrom)
+	}
+}
+
+func resolveMetricName(known map[string]MetricType, identifier string) (string, bool) {
+	if _, ok := known[identifier]; ok {
+		return identifier, true
+	}
+	for _, suffix := range histogramSeriesSuffixes {
+		family := strings.TrimSuffix(identifier, suffix)
+		if mType, ok := known[family]; ok && family != identifier && (mType == HistogramType || mType == SummaryType) {
+			return family, true
+		}
+	}
+	return "", false
+}
+
+func dedupSorted(names []string) []string {
+	var deduped []string
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			deduped = append(deduped, name)
+		}
+	}
+	return deduped
+}
@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MetricType is the normalized type of a metric, its value being the display name
+type MetricType string
+
+const (
+	CounterType   MetricType = "Counter"
+	GaugeType     MetricType = "Gauge"
+	HistogramType MetricType = "Histogram"
+	SummaryType   MetricType = "Summary"
+	UntypedType   MetricType = "Untyped"
+)
+
+var metricTypes = []MetricType{CounterType, GaugeType, HistogramType, SummaryType, UntypedType}
+
+// typeOrder is the order of the metric types when sorting by type
+var typeOrder = []MetricType{GaugeType, CounterType, HistogramType, SummaryType, UntypedType}
+
+// typeRank returns the position of the type in typeOrder, unknown types ranking last
+func typeRank(t MetricType) int {
+	if i := slices.Index(typeOrder, t); i >= 0 {
+		return i
+	}
+	return len(typeOrder)
+}
+
+// MetricTypes returns all the normalized metric types
+func MetricTypes() []MetricType {
+	return append([]MetricType(nil), metricTypes...)
+}
+
+// ParseMetricTypeName normalizes a type of the exposition format, e.g. "counter", or of
+// operatormetrics, e.g. "CounterVec", failing for unknown types
+func ParseMetricTypeName(name string) (MetricType, error) {
+	base := strings.TrimSuffix(name, "Vec")
+	for _, t := range metricTypes {
+		if strings.EqualFold(base, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown metric type %q", name)
+}
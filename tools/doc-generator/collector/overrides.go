@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LoadOverrides reads the file mapping metric names to the descriptions replacing the HELP text
+// they are registered with, e.g.
+//
+//	kubevirt_vmi_phase_count: Sum of VMIs per phase and node.
+func LoadOverrides(path string) (map[string]string, error) {
+	return loadMetricMapping(path, "overrides")
+}
+
+// LoadIDs reads the file mapping metric names to the stable IDs anchoring their documentation, e.g.
+//
+//	kubevirt_vmi_phase_count: vmi-phase-count
+func LoadIDs(path string) (map[string]string, error) {
+	return loadMetricMapping(path, "ids")
+}
+
+// LoadSourcePaths reads the file mapping metric names to the repository relative paths of the
+// files registering them, e.g.
+//
+//	kubevirt_vmi_phase_count: pkg/monitoring/metrics/virt-controller/vmistats/collector.go
+func LoadSourcePaths(path string) (map[string]string, error) {
+	return loadMetricMapping(path, "sources")
+}
+
+func loadMetricMapping(path string, kind string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping map[string]string
+	if err := yaml.UnmarshalStrict(content, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s of %s: %v", kind, path, err)
+	}
+	return mapping, nil
+}
+
+// applyOverrides replaces the description of the overridden metrics, failing if an override
+// doesn't match any metric so the overrides don't go stale
+func applyOverrides(metrics List, overrides map[string]string) error {
+	applied := make(map[string]bool, len(overrides))
+	for i := range metrics {
+		if description, ok := overrides[metrics[i].Name]; ok {
+			metrics[i].Description = description
+			metrics[i].Overridden = true
+			applied[metrics[i].Name] = true
+		}
+	}
+
+	return unmatchedError("overridden", overrides, applied)
+}
+
+// applyIDs sets the stable ID of the metrics, failing if an ID doesn't match any metric, isn't a valid
+// anchor or would be ambiguous as it is shared with another metric or is the name of another metric
+func applyIDs(metrics List, ids map[string]string) error {
+	names := make(map[string]bool, len(metrics))
+	for _, m := range metrics {
+		names[m.Name] = true
+	}
+
+	applied := make(map[string]bool, len(ids))
+	owners := make(map[string]string, len(ids))
+	for i := range metrics {
+		id, ok := ids[metrics[i].Name]
+		if !ok {
+			continue
+		}
+		if id == "" || strings.ContainsAny(id, " \t\n") {
+			return fmt.Errorf("id %q of %s is not a valid anchor", id, metrics[i].Name)
+		}
+		if owner, exists := owners[id]; exists {
+			return fmt.Errorf("metrics %s and %s share the id %s", owner, metrics[i].Name, id)
+		}
+		if id != metrics[i].Name && names[id] {
+			return fmt.Errorf("id %s of %s is the name of another metric", id, metrics[i].Name)
+		}
+		owners[id] = metrics[i].Name
+		metrics[i].ID = id
+		applied[metrics[i].Name] = true
+	}
+
+	return unmatchedError("identified", ids, applied)
+}
+
+// applySourcePaths sets the path of the file registering the metrics, failing if a path doesn't match
+// any metric or isn't relative to the repository root
+func applySourcePaths(metrics List, sourcePaths map[string]string) error {
+	applied := make(map[string]bool, len(sourcePaths))
+	for i := range metrics {
+		sourcePath, ok := sourcePaths[metrics[i].Name]
+		if !ok {
+			continue
+		}
+		if !filepath.IsLocal(sourcePath) {
+			return fmt.Errorf("source path %q of %s is not relative to the repository root", sourcePath, metrics[i].Name)
+		}
+		metrics[i].DefinedIn = filepath.ToSlash(filepath.Clean(sourcePath))
+		applied[metrics[i].Name] = true
+	}
+
+	return unmatchedError("sourced", sourcePaths, applied)
+}
+
+// unmatchedError fails listing the mapped metrics which weren't applied, so the mapping doesn't go stale
+func unmatchedError(kind string, mapping map[string]string, applied map[string]bool) error {
+	var unmatched []string
+	for name := range mapping {
+		if !applied[name] {
+			unmatched = append(unmatched, name)
+		}
+	}
+	if len(unmatched) > 0 {
+		sort.Strings(unmatched)
+		return fmt.Errorf("%s metrics not found: %s", kind, strings.Join(unmatched, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// parseMetricDesc splits a HELP line into the metric name, description, stability and deprecation
+// timeline, the description is empty when the line has none. Metrics with a deprecation timeline
+// are deprecated
+func parseMetricDesc(line string) Metric {
+	split := strings.Split(line, " ")
+	m := Metric{Name: split[2], Stability: Stable}
+	words := split[3:]
+	if len(words) == 0 {
+		return m
+	}
+	if level, ok := parseStabilityAnnotation(words[0]); ok {
+		m.Stability, words = level, words[1:]
+	}
+
+	var description string
+	description, m.DeprecatedInVersion, m.RemovedInVersion = parseDeprecationTimeline(strings.Join(words, " "))
+	if m.DeprecatedInVersion != "" || m.RemovedInVersion != "" {
+		m.Stability = Deprecated
+	}
+	m.Description = capitalize(helpUnescaper.Replace(description))
+	return m
+}
+
+// capitalize upper-cases the first rune of the text, leaving the rest untouched
+func capitalize(text string) string {
+	first, size := utf8.DecodeRuneInString(text)
+	if size == 0 {
+		return text
+	}
+	return string(unicode.ToUpper(first)) + text[size:]
+}
+
+// helpUnescaper reverts the escaping of backslashes and line feeds in HELP values of the text exposition format
+var helpUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+// indexMetricTypes maps the metric names to the type names of their TYPE lines, wherever the
+// lines are in the exposition, the first TYPE line of a metric taking precedence
+func indexMetricTypes(lines []string) map[string]string {
+	typeNames := map[string]string{}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+		if split := strings.Split(line, " "); len(split) > 3 {
+			if _, exists := typeNames[split[2]]; !exists {
+				typeNames[split[2]] = split[3]
+			}
+		}
+	}
+	return typeNames
+}
+
+// metricType resolves the type of the metric from the indexed TYPE lines, the type is empty if there is none
+func metricType(typeNames map[string]string, name string) (MetricType, error) {
+	typeName, ok := typeNames[name]
+	if !ok {
+		return "", nil
+	}
+	mType, err := ParseMetricTypeName(typeName)
+	if err != nil {
+		return "", fmt.Errorf("metric %s: %w", name, err)
+	}
+	return mType, nil
+}
+
+// DefaultPrefix is the prefix of the names of the KubeVirt metrics
+const DefaultPrefix = "kubevirt_"
+
+// HasAnyPrefix reports whether the metric name starts with any of the prefixes
+func HasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVirtMetrics appends the metrics of the exposition whose names start with one of the given prefixes
+func parseVirtMetrics(r io.Reader, metrics *List, prefixes []string) error {
+	return parseVirtMetricsSkipping(r, metrics, prefixes, nil)
+}
+
+// parseVirtMetricsSkipping is parseVirtMetrics additionally calling skipped, unless nil, with the name
+// of each metric family left out for not starting with any of the prefixes
+func parseVirtMetricsSkipping(r io.Reader, metrics *List, prefixes []string, skipped func(name string)) error {
+	families := map[string]int{}
+	// UNIT lines may precede the HELP line of their family, they are associated once all lines are read
+	units := map[string]string{}
+
+	var lines []string
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		lines = append(lines, scan.Text())
+	}
+	if scan.Err() != nil {
+		return fmt.Errorf("failed to parse metrics from prometheus endpoint, %w", scan.Err())
+	}
+	// the TYPE lines are indexed upfront as they don't necessarily follow the HELP line of their family
+	typeNames := indexMetricTypes(lines)
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "# HELP ") {
+			parsed := parseMetricDesc(line)
+			metName := parsed.Name
+			if parent, ok := parentFamily(families, *metrics, metName); ok {
+				// fold the sub-series into its parent
+				families[metName] = parent
+			} else if HasAnyPrefix(metName, prefixes) {
+				metType, err := metricType(typeNames, metName)
+				if err != nil {
+					return err
+				}
+				parsed.Type = metType
+				*metrics = append(*metrics, parsed)
+				families[metName] = len(*metrics) - 1
+			} else if skipped != nil {
+				skipped(metName)
+			}
+		} else if strings.HasPrefix(line, "# UNIT ") {
+			if split := strings.Split(line, " "); len(split) > 3 {
+				units[split[2]] = split[3]
+			}
+		} else if !strings.HasPrefix(line, "#") {
+			name, labels, value, err := parseSample(line)
+			if err != nil {
+				return err
+			}
+			if i, ok := sampleFamily(families, name); ok {
+				if err := (*metrics)[i].addSample(name, labels, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for name, unit := range units {
+		if i, ok := families[name]; ok {
+			(*metrics)[i].Unit = unit
+		}
+	}
+
+	if err := validateMetricTypes(*metrics); err != nil {
+		return err
+	}
+
+	sort.Sort(metrics)
+
+	return metrics.removeDuplicates()
+}
+
+// validateMetricTypes fails if any metric has no resolvable type, e.g. when a
+// collector exposes a HELP line without a matching TYPE line
+func validateMetricTypes(metrics List) error {
+	var untyped []string
+	for _, m := range metrics {
+		if m.Type == "" {
+			untyped = append(untyped, m.Name)
+		}
+	}
+
+	if len(untyped) > 0 {
+		sort.Strings(untyped)
+		return fmt.Errorf("failed to resolve the type of the following metrics: %s", strings.Join(untyped, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "kubevirt.io/api/core/v1"
+)
+
+const phaseCountMetricName = "kubevirt_vmi_phase_count"
+
+// vmiPhases are the phases a VMI can report, besides the unset one
+var vmiPhases = []v1.VirtualMachineInstancePhase{
+	v1.Pending,
+	v1.Scheduling,
+	v1.Scheduled,
+	v1.Running,
+	v1.Succeeded,
+	v1.Failed,
+	v1.Unknown,
+}
+
+var documentedPhasesPattern = regexp.MustCompile("can be one of the following: \\[([^]]*)\\]")
+
+// checkPhaseCount verifies the phases listed in the description of kubevirt_vmi_phase_count match the VMI phases
+func checkPhaseCount(metrics List) error {
+	for _, m := range metrics {
+		if m.Name == phaseCountMetricName {
+			if err := checkDocumentedPhases(m.Description); err != nil {
+				return fmt.Errorf("%s: %v", m.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkDocumentedPhases(description string) error {
+	match := documentedPhasesPattern.FindStringSubmatch(description)
+	if match == nil {
+		return fmt.Errorf("the description doesn't list the phases")
+	}
+
+	documented := map[string]bool{}
+	for _, phase := range strings.Split(match[1], ",") {
+		documented[strings.Trim(strings.TrimSpace(phase), "`")] = true
+	}
+
+	var missing []string
+	for _, phase := range vmiPhases {
+		if !documented[string(phase)] {
+			missing = append(missing, string(phase))
+		}
+		delete(documented, string(phase))
+	}
+
+	var unknown []string
+	for phase := range documented {
+		unknown = append(unknown, phase)
+	}
+
+	if len(missing) > 0 || len(unknown) > 0 {
+		return fmt.Errorf("the documented phases don't match the VMI phases, missing: [%s], unknown: [%s]",
+			strings.Join(missing, ", "), strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const bucketSuffix = "_bucket"
+
+// sampleSuffixes are the suffixes of the series exposed by histogram and summary metric families
+var sampleSuffixes = []string{bucketSuffix, "_sum", "_count"}
+
+// sampleLabels are labels added by the exposition format itself rather than by the metric
+var sampleLabels = map[string]bool{"le": true, "quantile": true}
+
+var labelValueUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n")
+
+// sampleFamily finds the metric family a sample belongs to, considering the
+// sub-series of histograms and summaries
+func sampleFamily(families map[string]int, sampleName string) (int, bool) {
+	if i, ok := families[sampleName]; ok {
+		return i, true
+	}
+	for _, suffix := range sampleSuffixes {
+		if i, ok := families[strings.TrimSuffix(sampleName, suffix)]; ok && strings.HasSuffix(sampleName, suffix) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// parentFamily finds the histogram or summary family the given family name is a sub-series of,
+// for expositions describing the _bucket, _sum and _count series as families of their own
+func parentFamily(families map[string]int, metrics List, name string) (int, bool) {
+	for _, suffix := range sampleSuffixes {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		if i, ok := families[strings.TrimSuffix(name, suffix)]; ok && (metrics[i].Type == HistogramType || metrics[i].Type == SummaryType) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// parseSample returns the metric name, the labels and the value of an exposition sample line,
+// e.g. `kubevirt_vmi_phase_count{node="node01",phase="running"} 1`
+func parseSample(line string) (string, map[string]string, string, error) {
+	open := strings.IndexAny(line, "{ ")
+	if open == -1 || line[open] == ' ' {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return "", nil, "", fmt.Errorf("failed to parse the empty sample %q", line)
+		}
+		return fields[0], nil, sampleValue(fields[1:]), nil
+	}
+
+	name := line[:open]
+	labels := map[string]string{}
+	rest := line[open+1:]
+	for {
+		rest = strings.TrimLeft(rest, ", ")
+		if strings.HasPrefix(rest, "}") {
+			return name, labels, sampleValue(strings.Fields(rest[1:])), nil
+		}
+
+		eq := strings.Index(rest, "=\"")
+		if eq == -1 {
+			return "", nil, "", fmt.Errorf("failed to parse labels of sample %q", line)
+		}
+		key := rest[:eq]
+
+		rest = rest[eq+2:]
+		end := closingQuote(rest)
+		if end == -1 {
+			return "", nil, "", fmt.Errorf("failed to parse labels of sample %q", line)
+		}
+		labels[key] = labelValueUnescaper.Replace(rest[:end])
+		rest = rest[end+1:]
+	}
+}
+
+// sampleValue returns the value among the fields following the name and labels of a sample, leaving
+// out the optional timestamp
+func sampleValue(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// closingQuote returns the index of the first unescaped double quote
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// addSample records the label keys of the sample, the first sample as the example of the metric and,
+// for histogram buckets and summary quantiles, the bucket boundary or the quantile
+func (m *Metric) addSample(sampleName string, labels map[string]string, value string) error {
+	m.addExample(sampleName, labels, value)
+	for key := range labels {
+		if !sampleLabels[key] {
+			m.addLabels(key)
+		}
+	}
+
+	if le, ok := labels["le"]; ok && sampleName == m.Name+bucketSuffix {
+		bucket, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse bucket boundary %q of %s, %w", le, m.Name, err)
+		}
+		m.addBuckets(bucket)
+	}
+
+	if q, ok := labels["quantile"]; ok && sampleName == m.Name && m.Type == SummaryType {
+		quantile, err := strconv.ParseFloat(q, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse quantile %q of %s, %w", q, m.Name, err)
+		}
+		m.addQuantiles(quantile)
+	}
+
+	return nil
+}
+
+// addBuckets merges the given boundaries into the sorted set of the histogram buckets
+func (m *Metric) addBuckets(buckets ...float64) {
+	m.Buckets = mergeSorted(m.Buckets, buckets...)
+}
+
+// addQuantiles merges the given quantiles into the sorted set of the summary quantiles
+func (m *Metric) addQuantiles(quantiles ...float64) {
+	m.Quantiles = mergeSorted(m.Quantiles, quantiles...)
+}
+
+// mergeSorted inserts the values missing from the sorted set
+func mergeSorted(set []float64, values ...float64) []float64 {
+	for _, value := range values {
+		i := sort.SearchFloat64s(set, value)
+		if i < len(set) && set[i] == value {
+			continue
+		}
+		set = append(set, 0)
+		copy(set[i+1:], set[i:])
+		set[i] = value
+	}
+	return set
+}
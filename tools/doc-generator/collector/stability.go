@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+)
+
+type Stability string
+
+const (
+	Stable     Stability = "STABLE"
+	Alpha      Stability = "ALPHA"
+	Deprecated Stability = "DEPRECATED"
+)
+
+// Deprecated reports whether the metric is deprecated, either through the [DEPRECATED] HELP annotation
+// or the stability set explicitly
+func (m Metric) Deprecated() bool {
+	return m.Stability == Deprecated
+}
+
+// stabilityLevelField is the operatormetrics.MetricOpts extra field holding the metric stability
+const stabilityLevelField = "StabilityLevel"
+
+var stabilityLevels = map[Stability]bool{
+	Stable:     true,
+	Alpha:      true,
+	Deprecated: true,
+}
+
+// StabilityLevels returns all the stability levels, from the most to the least stable
+func StabilityLevels() []Stability {
+	return []Stability{Stable, Alpha, Deprecated}
+}
+
+// parseStabilityAnnotation parses the "[ALPHA]" like annotation HELP texts can be prefixed with
+func parseStabilityAnnotation(word string) (Stability, bool) {
+	if !strings.HasPrefix(word, "[") || !strings.HasSuffix(word, "]") {
+		return "", false
+	}
+
+	level := Stability(strings.ToUpper(strings.Trim(word, "[]")))
+	return level, stabilityLevels[level]
+}
+
+// optsStability returns the stability set in the metric extra fields, defaulting to stable
+func optsStability(opts operatormetrics.MetricOpts) Stability {
+	level := Stability(strings.ToUpper(opts.ExtraFields[stabilityLevelField]))
+	if stabilityLevels[level] {
+		return level
+	}
+	return Stable
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	deprecatedInToken = "deprecated:"
+	removedInToken    = "removed:"
+)
+
+// bracketedAnnotation matches the bracketed annotations of HELP texts, e.g. "[deprecated:v1.2 removed:v1.4]"
+var bracketedAnnotation = regexp.MustCompile(`\s*\[([^\[\]]*)\]`)
+
+// parseDeprecationTimeline strips the first "[deprecated:v1.2 removed:v1.4]" like annotation of the
+// description, returning the versions it holds. Either token can be left out, annotations holding any
+// other token are left in the description untouched
+func parseDeprecationTimeline(description string) (string, string, string) {
+	for _, match := range bracketedAnnotation.FindAllStringSubmatchIndex(description, -1) {
+		deprecatedIn, removedIn, ok := parseTimelineTokens(description[match[2]:match[3]])
+		if ok {
+			stripped := strings.TrimSpace(description[:match[0]] + description[match[1]:])
+			return stripped, deprecatedIn, removedIn
+		}
+	}
+	return description, "", ""
+}
+
+func parseTimelineTokens(annotation string) (string, string, bool) {
+	tokens := strings.Fields(annotation)
+	if len(tokens) == 0 {
+		return "", "", false
+	}
+
+	var deprecatedIn, removedIn string
+	for _, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, deprecatedInToken) && deprecatedIn == "":
+			deprecatedIn = strings.TrimPrefix(token, deprecatedInToken)
+		case strings.HasPrefix(token, removedInToken) && removedIn == "":
+			removedIn = strings.TrimPrefix(token, removedInToken)
+		default:
+			return "", "", false
+		}
+	}
+	if deprecatedIn == "" && removedIn == "" {
+		return "", "", false
+	}
+	return deprecatedIn, removedIn, true
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// the subcommands of the doc-generator, generate being the default one
+const (
+	generateCommand = "generate"
+	validateCommand = "validate"
+)
+
+func main() {
+	command, args := splitCommand(os.Args[1:])
+	switch command {
+	case generateCommand:
+		runGenerate(args)
+	case validateCommand:
+		runValidate(args)
+	default:
+		exitOnError(fmt.Errorf("unknown command %q, expected one of: %s, %s", command, generateCommand, validateCommand))
+	}
+}
+
+// splitCommand returns the subcommand and its arguments, the subcommand defaulting to generate when
+// there are no arguments or the first one is a flag, so the invocations preceding the subcommands keep working
+func splitCommand(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return generateCommand, args
+	}
+	return args[0], args[1:]
+}
+
+// runValidate checks the metrics against the naming conventions and for missing types, without writing any output and failing on violations. The description style violations are
+// warnings unless -strict
+func runValidate(args []string) {
+	fs := flag.NewFlagSet(validateCommand, flag.ExitOnError)
+	shared := registerMetricsFlags(fs)
+	maxDescriptionLength := fs.Int("max-description-length", defaultMaxDescriptionLength, "maximum number of characters of the metric descriptions, no maximum when zero")
+	strict := fs.Bool("strict", false, "fail on the descriptions too long or not ending with punctuation instead of warning about them")
+	shared.parse(fs, args)
+
+	level := shared.logLevel()
+	metrics := shared.collectMetrics(level)
+	violations := validateMetrics(metrics, shared.prefixes())
+	descriptionViolations := lintDescriptions(metrics, *maxDescriptionLength)
+	if *strict {
+		violations = append(violations, descriptionViolations...)
+	} else {
+		for _, violation := range descriptionViolations {
+			fmt.Fprintln(level.warnings(), "warning:", violation)
+		}
+	}
+	reportViolations(violations)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const otherComponent = "Other"
+
+type component struct {
+	name     string
+	prefixes []string
+}
+
+// components maps metric name prefixes to the KubeVirt component the metrics are grouped under,
+// the groups are rendered in this order, followed by the otherComponent group
+var components = []component{
+	{name: "virt-api", prefixes: []string{"kubevirt_virt_api_"}},
+	{name: "virt-controller", prefixes: []string{"kubevirt_virt_controller_"}},
+	{name: "virt-handler", prefixes: []string{"kubevirt_virt_handler_"}},
+	{name: "virt-operator", prefixes: []string{"kubevirt_virt_operator_"}},
+	{name: "VM", prefixes: []string{"kubevirt_vm_"}},
+	{name: "VMI", prefixes: []string{"kubevirt_vmi_"}},
+	{name: "VMI Migration", prefixes: []string{"kubevirt_vmi_migration_", "kubevirt_vmi_migrations_"}},
+	{name: "VM Snapshot", prefixes: []string{"kubevirt_vmsnapshot_"}},
+}
+
+// metricComponent returns the component owning the metric, matching the longest known prefix
+func metricComponent(name string) string {
+	owner, longest := otherComponent, 0
+	for _, c := range components {
+		for _, prefix := range c.prefixes {
+			if len(prefix) > longest && strings.HasPrefix(name, prefix) {
+				owner, longest = c.name, len(prefix)
+			}
+		}
+	}
+	return owner
+}
+
+type metricGroup struct {
+	component string
+	metrics   collector.List
+}
+
+// groupByComponent splits the metrics into per component groups, in the sort order within each group,
+// empty groups are omitted
+func groupByComponent(m collector.List, order string) []metricGroup {
+	byComponent := map[string]collector.List{}
+	for _, met := range m {
+		c := metricComponent(met.Name)
+		byComponent[c] = append(byComponent[c], met)
+	}
+
+	var groups []metricGroup
+	for _, c := range append(components, component{name: otherComponent}) {
+		if metrics, ok := byComponent[c.name]; ok {
+			metrics = sortMetrics(metrics, order)
+			// deprecated metrics are listed last
+			sort.SliceStable(metrics, func(i, j int) bool {
+				return !metrics[i].Deprecated() && metrics[j].Deprecated()
+			})
+			groups = append(groups, metricGroup{component: c.name, metrics: metrics})
+		}
+	}
+	return groups
+}
+
+func (g metricGroup) writeToFile(newFile io.Writer) {
+	fmt.Fprintln(newFile, "##", g.component)
+	for _, m := range g.metrics {
+		writeMetric(newFile, m)
+	}
+}
+
+// writeSummary writes the total number of metrics and their breakdown per component
+func writeSummary(w io.Writer, groups []metricGroup) {
+	total := 0
+	for _, group := range groups {
+		total += len(group.metrics)
+	}
+
+	fmt.Fprintln(w, "## Summary")
+	fmt.Fprintf(w, "KubeVirt exposes %d metrics across %d components.\n\n", total, len(groups))
+	fmt.Fprintln(w, "| Component | Metrics |")
+	fmt.Fprintln(w, "|-----------|---------|")
+	for _, group := range groups {
+		fmt.Fprintf(w, "| %s | %d |\n", group.component, len(group.metrics))
+	}
+	fmt.Fprintln(w)
+}
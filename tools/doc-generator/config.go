@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// metricsConfig is the -config file bundling the metric governance settings in one place, e.g.
+//
+//	prefixes: [kubevirt_]
+//	exclude: [kubevirt_vmi_*_legacy]
+//	allowlist: [kubevirt_vmi_phase_count]
+//	overrides:
+//	  kubevirt_vmi_phase_count: Sum of VMIs per phase and node.
+type metricsConfig struct {
+	Prefixes  []string          `json:"prefixes,omitempty"`
+	Exclude   []string          `json:"exclude,omitempty"`
+	Allowlist []string          `json:"allowlist,omitempty"`
+	Overrides map[string]string `json:"overrides,omitempty"`
+}
+
+func loadConfig(path string) (*metricsConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &metricsConfig{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse the config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// applyConfig sets the settings of the config whose flags were neither passed on the command line
+// nor set from the environment, so both take precedence over the config, which takes precedence over the defaults
+func (f *metricsFlags) applyConfig(fs *flag.FlagSet, config *metricsConfig) {
+	passed := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) {
+		passed[fl.Name] = true
+	})
+
+	if len(config.Prefixes) > 0 && !passed["prefix"] {
+		*f.prefix = strings.Join(config.Prefixes, ",")
+	}
+	if len(config.Exclude) > 0 && !passed["exclude"] {
+		*f.exclude = strings.Join(config.Exclude, ",")
+	}
+	if !passed["allowlist"] {
+		f.allowedNames = config.Allowlist
+	}
+	if !passed["overrides"] {
+		f.descriptionOverrides = config.Overrides
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const formatCSV = "csv"
+
+var csvHeader = []string{"name", "type", "unit", "description"}
+
+// writeCSV writes a header and one row per metric in the sort order
+func writeCSV(w io.Writer, metrics collector.List, order string) error {
+	sorted := sortMetrics(metrics, order)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, m := range sorted {
+		if err := cw.Write([]string{m.Name, string(m.Type), m.Unit, m.Description}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// missingDescription is rendered instead of the empty description of a metric
+const missingDescription = "_(no description provided)_"
+
+// hasDescription reports whether the metric has a non-blank description
+func hasDescription(m collector.Metric) bool {
+	return strings.TrimSpace(m.Description) != ""
+}
+
+// undescribedError returns the error listing the metrics without a description, nil when all have one
+func undescribedError(metrics collector.List) error {
+	var undescribed []string
+	for _, m := range metrics {
+		if !hasDescription(m) {
+			undescribed = append(undescribed, m.Name)
+		}
+	}
+	if len(undescribed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the following metrics have no description: %s", strings.Join(undescribed, ", "))
+}
@@ -1,32 +1,28 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
-	"net/http/httptest"
+	"math"
 	"os"
-	"sort"
 	"strings"
+	"time"
 
-	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
-
-	domainstats "kubevirt.io/kubevirt/pkg/monitoring/domainstats/prometheus" // import for prometheus metrics
-	virt_api "kubevirt.io/kubevirt/pkg/monitoring/metrics/virt-api"
-	virt_controller "kubevirt.io/kubevirt/pkg/monitoring/metrics/virt-controller"
-	virt_operator "kubevirt.io/kubevirt/pkg/monitoring/metrics/virt-operator"
-	"kubevirt.io/kubevirt/pkg/monitoring/rules"
-	_ "kubevirt.io/kubevirt/pkg/virt-controller/watch"
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
 )
 
 // constant parts of the file
 const (
-	genFileComment = `<!--
+	genFileCommentStart = `<!--
 	This is an auto-generated file.
 	PLEASE DO NOT EDIT THIS FILE.
 	See "Developing new metrics" below how to generate this file
--->`
+`
+	genFileCommentEnd = "-->"
+
 	title      = "# KubeVirt metrics\n"
 	background = "This document aims to help users that are not familiar with all metrics exposed by different KubeVirt components.\n" +
 		"All metrics documented here are auto-generated by the utility tool `tools/doc-generator` and reflects exactly what is being exposed.\n\n"
@@ -35,10 +31,13 @@ const (
 		"### kubevirt_info\n" +
 		"Version information.\n\n"
 
-	opening = genFileComment + "\n\n" +
+	versionComment = "\tGenerated from KubeVirt version %s\n"
+
+	opening = "\n\n" +
 		title +
-		background +
-		KVSpecificMetrics
+		background
+
+	rulesNamespaceNote = "The recording rules documented here were evaluated against the `%s` namespace.\n\n"
 
 	// footer
 	footerHeading = "## Developing new metrics\n"
@@ -48,201 +47,465 @@ const (
 	footer = footerHeading + footerContent
 )
 
-func main() {
-	handler := domainstats.Handler(1)
-	RegisterFakeDomainCollector()
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
 
-	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
-	checkError(err)
+// metricsFlags are the flags shared by the subcommands to collect and filter the metrics
+type metricsFlags struct {
+	prefix                *string
+	strictDescriptions    *bool
+	strictPrefix          *bool
+	rulesNamespace        *string
+	allowlist             *string
+	exclude               *string
+	types                 *string
+	timeout               *time.Duration
+	retries               *int
+	endpoints             endpointList
+	overrides             *string
+	highCardinalityLabels *string
+	sources               *string
+	ids                   *string
+	quiet                 *bool
+	verbose               *bool
+	config                *string
+	// allowedNames and descriptionOverrides are the allowlist and overrides of the config file,
+	// used unless the -allowlist and -overrides files are passed
+	allowedNames         []string
+	descriptionOverrides map[string]string
+	// samples is set by the subcommands documenting an example sample of each metric
+	samples bool
+}
 
-	recorder := httptest.NewRecorder()
+func registerMetricsFlags(fs *flag.FlagSet) *metricsFlags {
+	f := &metricsFlags{}
+	f.prefix = fs.String("prefix", collector.DefaultPrefix, "comma separated list of the metric name prefixes to document")
+	f.strictDescriptions = fs.Bool("strict-descriptions", false, "fail on metrics without a description instead of rendering them with a placeholder and warning about them")
+	f.strictPrefix = fs.Bool("strict-prefix", false, "fail on scraped metrics not starting with any of the prefixes instead of leaving them out, except the process and Go runtime metrics")
+	f.rulesNamespace = fs.String("rules-namespace", "", "namespace the recording rules are evaluated against")
+	f.allowlist = fs.String("allowlist", "", "file listing the names of the only metrics to document, one per line, failing if any of them doesn't exist")
+	f.exclude = fs.String("exclude", "", "comma separated list of the metric names, or glob patterns, to leave out of the output")
+	f.types = fs.String("types", "", "comma separated list of the metric types to document, e.g. Counter,Gauge (default all)")
+	f.timeout = fs.Duration("timeout", collector.DefaultEndpointTimeout, "timeout of scraping each live metrics endpoint")
+	f.retries = fs.Int("retries", collector.DefaultEndpointRetries, "number of times scraping a live metrics endpoint is retried, with an exponential backoff, on connection and server errors")
+	fs.Var(&f.endpoints, "endpoint", "URL of a live metrics endpoint to document instead of the in-process fake collectors, can be repeated")
+	f.overrides = fs.String("overrides", "", "YAML file mapping metric names to the descriptions replacing their HELP text")
+	f.highCardinalityLabels = fs.String("high-cardinality-labels", strings.Join(collector.DefaultHighCardinalityLabels, ","), "comma separated list of the label keys flagging the metrics carrying them as high cardinality, none when empty")
+	f.sources = fs.String("sources", "", "YAML file mapping metric names to the repository relative paths of the files registering them, rendered as links")
+	f.ids = fs.String("ids", "", "YAML file mapping metric names to stable IDs, rendered as anchors which survive renaming the metrics")
+	f.quiet = fs.Bool("quiet", false, "only write errors to stderr, suppressing the warnings")
+	f.verbose = fs.Bool("verbose", false, "write the number of metrics per source and the filtered out metrics to stderr")
+	f.config = fs.String("config", "", "YAML file bundling the prefixes, exclude, allowlist and overrides, the flags taking precedence over it")
+	return f
+}
 
-	handler.ServeHTTP(recorder, req)
+// parse parses the arguments, the flags not passed falling back to their environment variables
+// and then to the -config file
+func (f *metricsFlags) parse(fs *flag.FlagSet, args []string) {
+	_ = fs.Parse(args)
+	exitOnError(applyEnvironment(fs, os.LookupEnv))
+	if *f.config == "" {
+		return
+	}
+	config, err := loadConfig(*f.config)
+	exitOnError(err)
+	f.applyConfig(fs, config)
+}
 
-	metrics := getMetricsNotIncludeInEndpointByDefault()
+func (f *metricsFlags) prefixes() []string {
+	return strings.Split(*f.prefix, ",")
+}
 
-	if status := recorder.Code; status == http.StatusOK {
-		err := parseVirtMetrics(recorder.Body, &metrics)
-		checkError(err)
+func (f *metricsFlags) logLevel() logLevel {
+	level, err := newLogLevel(*f.quiet, *f.verbose)
+	exitOnError(err)
+	return level
+}
 
-	} else {
-		panic(fmt.Errorf("got HTTP status code of %d from /metrics", recorder.Code))
+// collectMetrics collects the metrics and applies the allowlist, exclude and types filters to them
+func (f *metricsFlags) collectMetrics(level logLevel) collector.List {
+	var err error
+	descriptionOverrides := f.descriptionOverrides
+	if *f.overrides != "" {
+		descriptionOverrides, err = collector.LoadOverrides(*f.overrides)
+		exitOnError(err)
 	}
-	writeToFile(metrics)
-}
 
-func writeToFile(metrics metricList) {
-	newFile, err := os.Create("newmetrics.md")
-	checkError(err)
-	defer newFile.Close()
+	var metricIDs map[string]string
+	if *f.ids != "" {
+		metricIDs, err = collector.LoadIDs(*f.ids)
+		exitOnError(err)
+	}
+
+	var sourcePaths map[string]string
+	if *f.sources != "" {
+		sourcePaths, err = collector.LoadSourcePaths(*f.sources)
+		exitOnError(err)
+	}
 
-	fmt.Fprint(newFile, opening)
-	metrics.writeToFile(newFile)
+	var cardinalityLabels []string
+	if *f.highCardinalityLabels != "" {
+		cardinalityLabels = strings.Split(*f.highCardinalityLabels, ",")
+	}
 
-	fmt.Fprint(newFile, footer)
+	metrics, err := collector.CollectMetrics(collector.Options{
+		Endpoints:             f.endpoints,
+		Timeout:               *f.timeout,
+		Retries:               *f.retries,
+		Prefixes:              f.prefixes(),
+		StrictPrefix:          *f.strictPrefix,
+		RulesNamespace:        *f.rulesNamespace,
+		Overrides:             descriptionOverrides,
+		IDs:                   metricIDs,
+		SourcePaths:           sourcePaths,
+		HighCardinalityLabels: cardinalityLabels,
+		Samples:               f.samples,
+		Warnings:              level.warnings(),
+		Verbose:               level.verbose(),
+	})
+	exitOnError(err)
+
+	names := f.allowedNames
+	if *f.allowlist != "" {
+		names, err = loadAllowlist(*f.allowlist)
+		exitOnError(err)
+	}
+	if names != nil {
+		kept, err := allowMetrics(metrics, names)
+		exitOnError(err)
+		level.logRemoved("not allowlisted", metrics, kept)
+		metrics = kept
+	}
 
-}
+	if *f.exclude != "" {
+		kept, err := excludeMetrics(metrics, strings.Split(*f.exclude, ","))
+		exitOnError(err)
+		level.logRemoved("excluded", metrics, kept)
+		metrics = kept
+	}
 
-type metric struct {
-	name        string
-	description string
-	mType       string
+	if *f.types != "" {
+		included, err := parseMetricTypeNames(strings.Split(*f.types, ","))
+		exitOnError(err)
+		kept := filterTypes(metrics, included)
+		level.logRemoved("filtered out by type", metrics, kept)
+		metrics = kept
+	}
+
+	if err := undescribedError(metrics); err != nil {
+		if *f.strictDescriptions {
+			exitOnError(err)
+		}
+		fmt.Fprintln(level.warnings(), "warning:", err)
+	}
+	return metrics
 }
 
-func (m metric) writeToFile(newFile io.WriteCloser) {
-	fmt.Fprintln(newFile, "###", m.name)
-	fmt.Fprintln(newFile, m.description, "Type:", m.mType+".")
-	fmt.Fprintln(newFile)
+// runGenerate writes the documentation of the metrics, or checks it is up to date
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet(generateCommand, flag.ExitOnError)
+	shared := registerMetricsFlags(fs)
+	format := fs.String("format", formatMarkdown, "output format, one of: markdown, json, jsonschema, openmetrics-meta, csv")
+	output := fs.String("output", "", "output file, use - for stdout (default newmetrics.md, newmetrics.json, newmetrics.schema.json, newmetrics.txt or newmetrics.csv depending on the format)")
+	layout := fs.String("layout", layoutHeadings, "layout of the markdown output, one of: headings, table")
+	toc := fs.Bool("toc", true, "include a table of contents in the markdown output, ignored by the table layout")
+	summary := fs.Bool("summary", true, "include a summary of the metrics count per component in the markdown output")
+	sortOrder := fs.String("sort", sortByName, "order of the metrics, one of: name, type, the markdown headings layout keeping them grouped per component")
+	check := fs.Bool("check", false, "compare the generated content with the output file instead of writing it, failing if they differ")
+	version := fs.String("version", "", "KubeVirt version or commit recorded in the header comment of the markdown output")
+	includeAlerts := fs.Bool("include-alerts", false, "document the alerting rules in a separate section of the markdown output")
+	includeSamples := fs.Bool("include-samples", false, "document an example sample of each scraped metric, the label values looking like identifiers redacted")
+	verify := fs.Bool("verify", false, "compare the metrics exposed by the live -endpoint URLs with the ones documented in the output file, of the headings layout, instead of writing it, failing if any is undocumented or not exposed")
+	base := fs.String("base", "", "JSON metrics of a previous release, generated with -format=json, to write the metrics added, removed and changed since then to the -output file, or stdout, instead of the documentation")
+	shared.parse(fs, args)
+
+	level := shared.logLevel()
+	exitOnError(validateSortOrder(*sortOrder))
+	if *verify {
+		verifyFile(collector.Options{Endpoints: shared.endpoints, Timeout: *shared.timeout, Retries: *shared.retries, Prefixes: shared.prefixes(), StrictPrefix: *shared.strictPrefix, Verbose: level.verbose()}, *format, *output)
+		return
+	}
+
+	shared.samples = *includeSamples
+	metrics := shared.collectMetrics(level)
+	if *base != "" {
+		writeChangelog(metrics, *base, *output)
+		return
+	}
+	opts := renderOptions{format: *format, layout: *layout, toc: *toc, summary: *summary, rulesNamespace: *shared.rulesNamespace, version: *version, sortOrder: *sortOrder}
+	if *includeAlerts {
+		var err error
+		opts.alerts, err = collector.CollectAlerts(*shared.rulesNamespace)
+		exitOnError(err)
+	}
+	if *check {
+		checkFile(metrics, opts, *output)
+		return
+	}
+	writeToFile(metrics, opts, *output)
 }
 
-type metricList []metric
-
-// Len implements sort.Interface.Len
-func (m metricList) Len() int {
-	return len(m)
-}
-
-// Less implements sort.Interface.Less
-func (m metricList) Less(i, j int) bool {
-	return m[i].name < m[j].name
-}
-
-// Swap implements sort.Interface.Swap
-func (m metricList) Swap(i, j int) {
-	m[i], m[j] = m[j], m[i]
-}
-
-func (m metricList) writeToFile(newFile io.WriteCloser) {
-	for _, met := range m {
-		met.writeToFile(newFile)
-	}
-}
-
-func getMetricsNotIncludeInEndpointByDefault() metricList {
-	metrics := metricList{
-		{
-			name:        domainstats.MigrateVmiDataProcessedMetricName,
-			description: "The total Guest OS data processed and migrated to the new VM.",
-			mType:       "Gauge",
-		},
-		{
-			name:        domainstats.MigrateVmiDataRemainingMetricName,
-			description: "The remaining guest OS data to be migrated to the new VM.",
-			mType:       "Gauge",
-		},
-		{
-			name:        domainstats.MigrateVmiDirtyMemoryRateMetricName,
-			description: "The rate of memory being dirty in the Guest OS.",
-			mType:       "Gauge",
-		},
-		{
-			name:        domainstats.MigrateVmiMemoryTransferRateMetricName,
-			description: "The rate at which the memory is being transferred.",
-			mType:       "Gauge",
-		},
-		{
-			name:        "kubevirt_vmi_phase_count",
-			description: "Sum of VMIs per phase and node. `phase` can be one of the following: [`Pending`, `Scheduling`, `Scheduled`, `Running`, `Succeeded`, `Failed`, `Unknown`].",
-			mType:       "Gauge",
-		},
-		{
-			name:        "kubevirt_vmi_non_evictable",
-			description: "Indication for a VirtualMachine that its eviction strategy is set to Live Migration but is not migratable.",
-			mType:       "Gauge",
-		},
-	}
-
-	err := virt_controller.SetupMetrics(nil, nil, nil, nil, nil, nil, nil, nil)
-	checkError(err)
-	for _, m := range virt_controller.ListMetrics() {
-		metrics = append(metrics, newMetric(m))
+// stdoutOutput is the output value meaning the generated content is written to stdout
+const stdoutOutput = "-"
+
+func writeToFile(metrics collector.List, opts renderOptions, output string) {
+	if output == stdoutOutput {
+		checkError(render(os.Stdout, metrics, opts))
+		return
 	}
 
-	err = virt_api.SetupMetrics()
+	fileName, err := outputPath(opts.format, output)
 	checkError(err)
-	for _, m := range virt_api.ListMetrics() {
-		metrics = append(metrics, newMetric(m))
-	}
 
-	err = virt_operator.SetupMetrics()
+	newFile, err := os.Create(fileName)
 	checkError(err)
-	for _, m := range virt_operator.ListMetrics() {
-		metrics = append(metrics, newMetric(m))
+	defer newFile.Close()
+
+	checkError(render(newFile, metrics, opts))
+}
+
+// checkFile compares the generated content with the existing output file and
+// exits with a non-zero code, printing the differences, if they don't match
+func checkFile(metrics collector.List, opts renderOptions, output string) {
+	if output == stdoutOutput {
+		checkError(fmt.Errorf("check mode requires an output file"))
 	}
 
-	err = rules.SetupRules("")
+	fileName, err := outputPath(opts.format, output)
 	checkError(err)
 
-	for _, rule := range rules.ListRecordingRules() {
-		metrics = append(metrics, metric{
-			name:        rule.GetOpts().Name,
-			description: rule.GetOpts().Help,
-			mType:       string(rule.GetType()),
-		})
+	existing, err := os.ReadFile(fileName)
+	checkError(err)
+
+	var generated bytes.Buffer
+	checkError(render(&generated, metrics, opts))
+
+	if diff := unifiedDiff(fileName, "generated", string(existing), generated.String()); diff != "" {
+		fmt.Fprintf(os.Stderr, "%s is out of date, please run `make generate`:\n%s", fileName, diff)
+		os.Exit(1)
 	}
+}
 
-	return metrics
+// reportViolations prints each violation and exits with a non-zero code if there are any
+func reportViolations(violations []string) {
+	for _, violation := range violations {
+		fmt.Fprintln(os.Stderr, violation)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
+	}
 }
 
-func newMetric(om operatormetrics.Metric) metric {
-	return metric{
-		name:        om.GetOpts().Name,
-		description: om.GetOpts().Help,
-		mType:       strings.Replace(string(om.GetType()), "Vec", "", 1),
+func outputPath(format string, output string) (string, error) {
+	if output != "" {
+		return output, nil
 	}
+	return outputFileName(format)
 }
 
-func parseMetricDesc(line string) (string, string) {
-	split := strings.Split(line, " ")
-	name := split[2]
-	split[3] = strings.Title(split[3])
-	description := strings.Join(split[3:], " ")
-	return name, description
+func outputFileName(format string) (string, error) {
+	switch format {
+	case formatMarkdown:
+		return "newmetrics.md", nil
+	case formatJSON:
+		return "newmetrics.json", nil
+	case formatJSONSchema:
+		return "newmetrics.schema.json", nil
+	case formatOpenMetricsMeta:
+		return "newmetrics.txt", nil
+	case formatCSV:
+		return "newmetrics.csv", nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q", format)
+	}
 }
 
-func parseMetricType(scan *bufio.Scanner, name string) string {
-	for scan.Scan() {
-		typeLine := scan.Text()
-		if strings.HasPrefix(typeLine, "# TYPE ") {
-			split := strings.Split(typeLine, " ")
-			if split[2] == name {
-				return strings.Title(split[3])
-			}
-		}
+type renderOptions struct {
+	format         string
+	layout         string
+	toc            bool
+	summary        bool
+	rulesNamespace string
+	version        string
+	// sortOrder is the order of the metrics, sortByName when empty
+	sortOrder string
+	// alerts are rendered after the metrics of the markdown output, no section is written when nil
+	alerts []collector.Alert
+}
+
+// render serializes the metrics in the requested format
+func render(w io.Writer, metrics collector.List, opts renderOptions) error {
+	if err := validateSortOrder(opts.sortOrder); err != nil {
+		return err
+	}
+	switch opts.format {
+	case formatMarkdown:
+		return writeMarkdown(w, metrics, opts)
+	case formatJSON:
+		return writeJSON(w, metrics, opts.sortOrder)
+	case formatJSONSchema:
+		return writeJSONSchema(w)
+	case formatOpenMetricsMeta:
+		return writeOpenMetricsMeta(w, metrics, opts.sortOrder)
+	case formatCSV:
+		return writeCSV(w, metrics, opts.sortOrder)
+	default:
+		return fmt.Errorf("unsupported output format %q", opts.format)
 	}
-	return ""
 }
 
-const filter = "kubevirt_"
+func writeMarkdown(w io.Writer, metrics collector.List, opts renderOptions) error {
+	if opts.layout != layoutHeadings && opts.layout != layoutTable {
+		return fmt.Errorf("unsupported markdown layout %q", opts.layout)
+	}
 
-func parseVirtMetrics(r io.Reader, metrics *metricList) error {
-	scan := bufio.NewScanner(r)
-	for scan.Scan() {
-		helpLine := scan.Text()
-		if strings.HasPrefix(helpLine, "# HELP ") {
-			if strings.Contains(helpLine, filter) {
-				metName, metDesc := parseMetricDesc(helpLine)
-				metType := parseMetricType(scan, metName)
-				*metrics = append(*metrics, metric{name: metName, description: metDesc, mType: metType})
-			}
+	groups := groupByComponent(metrics, opts.sortOrder)
+
+	fmt.Fprint(w, genFileCommentStart)
+	if opts.version != "" {
+		fmt.Fprintf(w, versionComment, opts.version)
+	}
+	fmt.Fprint(w, genFileCommentEnd+opening)
+	if opts.rulesNamespace != "" {
+		fmt.Fprintf(w, rulesNamespaceNote, opts.rulesNamespace)
+	}
+
+	if opts.layout == layoutTable {
+		writeTable(w, metrics, opts.sortOrder)
+	} else {
+		if opts.toc {
+			writeTOC(w, groups, opts.alerts)
+		}
+		fmt.Fprint(w, KVSpecificMetrics)
+		for _, group := range groups {
+			group.writeToFile(w)
 		}
 	}
 
-	if scan.Err() != nil {
-		return fmt.Errorf("failed to parse metrics from prometheus endpoint, %w", scan.Err())
+	if opts.alerts != nil {
+		writeAlerts(w, opts.alerts)
 	}
 
-	sort.Sort(metrics)
+	if opts.summary {
+		writeSummary(w, groups)
+	}
+
+	fmt.Fprint(w, footer)
+	return nil
+}
+
+type jsonMetric struct {
+	Name                  string    `json:"name"`
+	Description           string    `json:"description"`
+	Type                  string    `json:"type"`
+	Unit                  string    `json:"unit,omitempty"`
+	Labels                []string  `json:"labels,omitempty"`
+	Stability             string    `json:"stability"`
+	Source                string    `json:"source,omitempty"`
+	Overridden            bool      `json:"overridden,omitempty"`
+	ID                    string    `json:"id,omitempty"`
+	DerivedFrom           []string  `json:"derivedFrom,omitempty"`
+	DeprecatedIn          string    `json:"deprecatedIn,omitempty"`
+	RemovedIn             string    `json:"removedIn,omitempty"`
+	DefinedIn             string    `json:"definedIn,omitempty"`
+	HighCardinalityLabels []string  `json:"highCardinalityLabels,omitempty"`
+	Buckets               []float64 `json:"buckets,omitempty"`
+	Quantiles             []float64 `json:"quantiles,omitempty"`
+	Example               string    `json:"example,omitempty"`
+}
 
-	// remove duplicates
-	for i := 0; i < len(*metrics)-1; i++ {
-		if (*metrics)[i].name == (*metrics)[i+1].name {
-			*metrics = append((*metrics)[:i], (*metrics)[i+1:]...)
-			i--
+func writeJSON(w io.Writer, metrics collector.List, order string) error {
+	sorted := sortMetrics(metrics, order)
+
+	jsonMetrics := make([]jsonMetric, 0, len(sorted))
+	for _, m := range sorted {
+		jsonMetrics = append(jsonMetrics, jsonMetric{Name: m.Name, Description: m.Description, Type: string(m.Type), Unit: m.Unit, Labels: m.Labels, Stability: string(m.Stability), Source: m.Source, Overridden: m.Overridden, ID: m.ID, DerivedFrom: m.DerivedFrom, DeprecatedIn: m.DeprecatedInVersion, RemovedIn: m.RemovedInVersion, DefinedIn: m.DefinedIn, HighCardinalityLabels: m.HighCardinalityLabels, Buckets: finiteBuckets(m.Buckets), Quantiles: m.Quantiles, Example: exampleLine(m)})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonMetrics)
+}
+
+// finiteBuckets returns the bucket boundaries without the +Inf one, which every histogram has and JSON can't represent
+func finiteBuckets(buckets []float64) []float64 {
+	var finite []float64
+	for _, bucket := range buckets {
+		if !math.IsInf(bucket, 1) {
+			finite = append(finite, bucket)
 		}
 	}
+	return finite
+}
 
-	return nil
+func writeMetric(newFile io.Writer, m collector.Metric) {
+	if m.ID != "" {
+		// the anchor precedes the heading so links to the ID keep working when the metric is renamed
+		fmt.Fprintln(newFile, idAnchor(m.ID))
+	}
+	fmt.Fprintln(newFile, "###", m.Name)
+	clauses := []string{missingDescription}
+	if hasDescription(m) {
+		clauses[0] = sentence(escapeMarkdown(m.Description))
+	}
+	if m.Deprecated() {
+		clauses[0] = deprecatedBadge + clauses[0]
+	}
+	if m.Type != "" {
+		clauses = append(clauses, "Type: "+string(m.Type)+".")
+	}
+	if m.Unit != "" {
+		clauses = append(clauses, "Unit: "+m.Unit+".")
+	}
+	fmt.Fprintln(newFile, strings.Join(clauses, " "))
+	if m.Source != "" {
+		fmt.Fprintln(newFile, "Stability:", string(m.Stability)+".", "Source:", m.Source+".")
+	} else {
+		fmt.Fprintln(newFile, "Stability:", string(m.Stability)+".")
+	}
+	if note := deprecationNote(m); note != "" {
+		fmt.Fprintln(newFile, note)
+	}
+	if len(m.Labels) > 0 {
+		fmt.Fprintln(newFile, "Labels:", "`"+strings.Join(m.Labels, "`, `")+"`.")
+	}
+	if len(m.HighCardinalityLabels) > 0 {
+		fmt.Fprintln(newFile, "High cardinality:", "`"+strings.Join(m.HighCardinalityLabels, "`, `")+"`.")
+	}
+	if len(m.DerivedFrom) > 0 {
+		fmt.Fprintln(newFile, "Derived from:", "`"+strings.Join(m.DerivedFrom, "`, `")+"`.")
+	}
+	if m.DefinedIn != "" {
+		fmt.Fprintln(newFile, "Defined in:", sourceLink(m.DefinedIn)+".")
+	}
+	if len(m.Buckets) > 0 {
+		fmt.Fprintln(newFile, "Buckets:", formatBuckets(m.Buckets)+".")
+	}
+	if len(m.Quantiles) > 0 {
+		fmt.Fprintln(newFile, "Quantiles:", formatBuckets(m.Quantiles)+".")
+	}
+	if m.Example != nil {
+		fmt.Fprintln(newFile, "Example:", "`"+exampleLine(m)+"`.")
+	}
+	fmt.Fprintln(newFile)
+}
+
+// exampleLine returns the example sample of the metric as an exposition line, empty if it has none
+func exampleLine(m collector.Metric) string {
+	if m.Example == nil {
+		return ""
+	}
+	return m.Example.String()
+}
+
+// exitOnError prints the error and exits with a non-zero code, for errors caused by the input rather than bugs
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
 func checkError(err error) {
@@ -0,0 +1,771 @@
+/*
+ * This file is part of the KubeVirt project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright The KubeVirt Authors.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+var _ = Describe("doc-generator", func() {
+	Context("version", func() {
+		It("should record the version inside the header comment", func() {
+			var out bytes.Buffer
+			Expect(render(&out, collector.List{}, renderOptions{format: formatMarkdown, layout: layoutHeadings, version: "v1.2.3"})).To(Succeed())
+			Expect(out.String()).To(HavePrefix(genFileCommentStart + "\tGenerated from KubeVirt version v1.2.3\n" + genFileCommentEnd))
+		})
+
+		It("should omit the version line when unset", func() {
+			var out bytes.Buffer
+			Expect(render(&out, collector.List{}, renderOptions{format: formatMarkdown, layout: layoutHeadings})).To(Succeed())
+			Expect(out.String()).To(HavePrefix(genFileCommentStart + genFileCommentEnd + "\n\n" + title))
+			Expect(out.String()).ToNot(ContainSubstring("Generated from KubeVirt version"))
+		})
+	})
+
+	Context("types", func() {
+		It("should only keep the metrics of the selected types", func() {
+			metrics := collector.List{
+				{Name: "kubevirt_a", Type: collector.GaugeType},
+				{Name: "kubevirt_b_seconds", Type: collector.HistogramType},
+				{Name: "kubevirt_c_total", Type: collector.CounterType},
+			}
+			types, err := parseMetricTypeNames([]string{"Gauge"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filterTypes(metrics, types)).To(Equal(collector.List{{Name: "kubevirt_a", Type: collector.GaugeType}}))
+		})
+
+		It("should fail on unknown types", func() {
+			_, err := parseMetricTypeNames([]string{"Gauge", "Meter"})
+			Expect(err).To(MatchError(`unknown metric type "Meter"`))
+		})
+	})
+
+	Context("sort", func() {
+		metrics := collector.List{
+			{Name: "kubevirt_d_seconds", Type: collector.HistogramType},
+			{Name: "kubevirt_b_total", Type: collector.CounterType},
+			{Name: "kubevirt_e_seconds", Type: collector.SummaryType},
+			{Name: "kubevirt_c", Type: collector.GaugeType},
+			{Name: "kubevirt_a_total", Type: collector.CounterType},
+			{Name: "kubevirt_f", Type: collector.GaugeType},
+		}
+
+		DescribeTable("should order the metrics", func(order string, expected []string) {
+			var names []string
+			for _, m := range sortMetrics(metrics, order) {
+				names = append(names, m.Name)
+			}
+			Expect(names).To(Equal(expected))
+		},
+			Entry("by name by default", "", []string{"kubevirt_a_total", "kubevirt_b_total", "kubevirt_c", "kubevirt_d_seconds", "kubevirt_e_seconds", "kubevirt_f"}),
+			Entry("by name", sortByName, []string{"kubevirt_a_total", "kubevirt_b_total", "kubevirt_c", "kubevirt_d_seconds", "kubevirt_e_seconds", "kubevirt_f"}),
+			Entry("by type then name", sortByType, []string{"kubevirt_c", "kubevirt_f", "kubevirt_a_total", "kubevirt_b_total", "kubevirt_d_seconds", "kubevirt_e_seconds"}),
+		)
+
+		It("should leave the metrics untouched", func() {
+			sortMetrics(metrics, sortByType)
+			Expect(metrics[0].Name).To(Equal("kubevirt_d_seconds"))
+		})
+
+		It("should apply to the rendered output", func() {
+			var out strings.Builder
+			Expect(render(&out, metrics, renderOptions{format: formatCSV, sortOrder: sortByType})).To(Succeed())
+			Expect(strings.Split(strings.TrimSpace(out.String()), "\n")[1:3]).To(Equal([]string{"kubevirt_c,Gauge,,", "kubevirt_f,Gauge,,"}))
+		})
+
+		It("should fail on unknown orders", func() {
+			Expect(render(io.Discard, metrics, renderOptions{format: formatCSV, sortOrder: "stability"})).To(MatchError(`unsupported sort order "stability"`))
+		})
+	})
+
+	Context("exclude", func() {
+		It("should leave the excluded metrics out of the output", func() {
+			metrics := collector.List{
+				{Name: "kubevirt_vmi_non_evictable", Type: collector.GaugeType, Stability: collector.Stable},
+				{Name: "kubevirt_vmi_phase_count", Type: collector.GaugeType, Stability: collector.Stable},
+			}
+			metrics, err := excludeMetrics(metrics, []string{"kubevirt_vmi_non_evictable"})
+			Expect(err).ToNot(HaveOccurred())
+
+			var out bytes.Buffer
+			Expect(render(&out, metrics, renderOptions{format: formatMarkdown, layout: layoutHeadings})).To(Succeed())
+			Expect(out.String()).ToNot(ContainSubstring("kubevirt_vmi_non_evictable"))
+			Expect(out.String()).To(ContainSubstring("### kubevirt_vmi_phase_count"))
+		})
+
+		It("should match glob patterns", func() {
+			metrics := collector.List{{Name: "kubevirt_a_internal"}, {Name: "kubevirt_b_internal"}, {Name: "kubevirt_c"}}
+			metrics, err := excludeMetrics(metrics, []string{"kubevirt_*_internal"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metrics).To(Equal(collector.List{{Name: "kubevirt_c"}}))
+		})
+
+		It("should fail when an excluded metric doesn't exist", func() {
+			_, err := excludeMetrics(collector.List{{Name: "kubevirt_a"}}, []string{"kubevirt_a", "kubevirt_missing"})
+			Expect(err).To(MatchError("excluded metrics not found: kubevirt_missing"))
+		})
+	})
+
+	DescribeTable("outputPath should default to a file name matching the format", func(format, output, expected string) {
+		Expect(outputPath(format, output)).To(Equal(expected))
+	},
+		Entry("markdown", formatMarkdown, "", "newmetrics.md"),
+		Entry("json", formatJSON, "", "newmetrics.json"),
+		Entry("csv", formatCSV, "", "newmetrics.csv"),
+		Entry("explicit output", formatJSON, "docs/metrics.json", "docs/metrics.json"),
+	)
+
+	It("outputPath should fail on unsupported formats", func() {
+		_, err := outputPath("yaml", "")
+		Expect(err).To(MatchError(`unsupported output format "yaml"`))
+	})
+
+	Context("changelog", func() {
+		base := []jsonMetric{
+			{Name: "kubevirt_a", Description: "The a metric.", Type: "Gauge"},
+			{Name: "kubevirt_b", Description: "The b metric.", Type: "Gauge"},
+			{Name: "kubevirt_c", Description: "The c metric.", Type: "Gauge"},
+			{Name: "kubevirt_removed", Description: "Gone.", Type: "Counter"},
+		}
+
+		It("should categorize the metrics added, removed and changed since the base", func() {
+			current := collector.List{
+				{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType},
+				{Name: "kubevirt_added", Description: "New.", Type: collector.GaugeType},
+				{Name: "kubevirt_b", Description: "The b metric.", Type: collector.CounterType},
+				{Name: "kubevirt_c", Description: "The reworded c metric.", Type: collector.HistogramType},
+			}
+
+			var out strings.Builder
+			compareVersions(base, current).write(&out)
+			Expect(out.String()).To(Equal("### Added\n- `kubevirt_added`\n\n" +
+				"### Removed\n- `kubevirt_removed`\n\n" +
+				"### Changed\n- `kubevirt_b`: type changed from Gauge to Counter\n" +
+				"- `kubevirt_c`: type changed from Gauge to Histogram, description changed\n\n"))
+		})
+
+		It("should report no changes for the same metrics", func() {
+			current := collector.List{{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType}}
+
+			var out strings.Builder
+			compareVersions(base[:1], current).write(&out)
+			Expect(out.String()).To(Equal("No metric changes.\n"))
+		})
+
+		It("should load the base from the JSON output", func() {
+			var generated bytes.Buffer
+			Expect(writeJSON(&generated, collector.List{{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable}}, sortByName)).To(Succeed())
+			path := filepath.Join(GinkgoT().TempDir(), "newmetrics.json")
+			Expect(os.WriteFile(path, generated.Bytes(), 0o600)).To(Succeed())
+
+			loaded, err := loadBaseMetrics(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compareVersions(loaded, collector.List{{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType}})).To(Equal(changelog{}))
+		})
+
+		It("should fail on a base which isn't the JSON output", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "newmetrics.md")
+			Expect(os.WriteFile(path, []byte("# KubeVirt metrics\n"), 0o600)).To(Succeed())
+			_, err := loadBaseMetrics(path)
+			Expect(err).To(MatchError(ContainSubstring("it must be generated with -format=json")))
+		})
+	})
+
+	Context("missing descriptions", func() {
+		metrics := collector.List{
+			{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable},
+			{Name: "kubevirt_b", Type: collector.GaugeType, Stability: collector.Stable},
+			{Name: "kubevirt_c", Description: " ", Type: collector.CounterType, Stability: collector.Stable},
+		}
+
+		It("should be rendered with a placeholder", func() {
+			var out strings.Builder
+			writeMetric(&out, metrics[1])
+			Expect(out.String()).To(Equal("### kubevirt_b\n_(no description provided)_ Type: Gauge.\nStability: STABLE.\n\n"))
+
+			out.Reset()
+			writeTable(&out, metrics, sortByName)
+			Expect(out.String()).To(ContainSubstring("| `kubevirt_c` | Counter | _(no description provided)_ |\n"))
+		})
+
+		It("should be listed in the error", func() {
+			Expect(undescribedError(metrics)).To(MatchError("the following metrics have no description: kubevirt_b, kubevirt_c"))
+			Expect(undescribedError(metrics[:1])).To(Succeed())
+		})
+	})
+
+	Context("allowlist", func() {
+		metrics := collector.List{{Name: "kubevirt_a"}, {Name: "kubevirt_b"}, {Name: "kubevirt_c"}}
+
+		It("should only keep the allowlisted metrics", func() {
+			names, err := parseAllowlist(strings.NewReader("# published metrics\nkubevirt_c\n\n  kubevirt_a\n"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"kubevirt_c", "kubevirt_a"}))
+
+			kept, err := allowMetrics(metrics, names)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kept).To(Equal(collector.List{{Name: "kubevirt_a"}, {Name: "kubevirt_c"}}))
+		})
+
+		It("should fail when an allowlisted metric doesn't exist", func() {
+			_, err := allowMetrics(metrics, []string{"kubevirt_missing", "kubevirt_a", "kubevirt_gone"})
+			Expect(err).To(MatchError("allowlisted metrics not found: kubevirt_gone, kubevirt_missing"))
+		})
+
+		It("should be loaded from a file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "allowlist.txt")
+			Expect(os.WriteFile(path, []byte("kubevirt_b\n"), 0o600)).To(Succeed())
+			Expect(loadAllowlist(path)).To(Equal([]string{"kubevirt_b"}))
+		})
+	})
+
+	Context("writeMetric", func() {
+		It("should escape the description", func() {
+			var out bytes.Buffer
+			writeMetric(&out, collector.Metric{Name: "kubevirt_test_metric", Description: "First line.\nSecond line with a \\ backslash.", Type: collector.GaugeType, Stability: collector.Stable})
+			Expect(out.String()).To(Equal("### kubevirt_test_metric\n" +
+				"First line.\nSecond line with a \\\\ backslash. Type: Gauge.\n" +
+				"Stability: STABLE.\n\n"))
+		})
+
+		DescribeTable("should render the description, type and unit as sentences", func(m collector.Metric, expected string) {
+			var out strings.Builder
+			writeMetric(&out, m)
+			Expect(strings.Split(out.String(), "\n")[1]).To(Equal(expected))
+		},
+			Entry("with a trailing period", collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType}, "The a metric. Type: Gauge."),
+			Entry("without a trailing period", collector.Metric{Name: "kubevirt_a", Description: "The a metric", Type: collector.GaugeType}, "The a metric. Type: Gauge."),
+			Entry("with a unit", collector.Metric{Name: "kubevirt_a_seconds", Description: "The a metric", Type: collector.GaugeType, Unit: "seconds"},
+				"The a metric. Type: Gauge. Unit: seconds."),
+			Entry("without a type", collector.Metric{Name: "kubevirt_a", Description: "The a metric"}, "The a metric."),
+		)
+
+		It("should render the source when known", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable, Source: "virt-controller"})
+			Expect(out.String()).To(Equal("### kubevirt_a\nThe a metric. Type: Gauge.\nStability: STABLE. Source: virt-controller.\n\n"))
+		})
+
+		It("should render the bucket boundaries", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a_seconds", Description: "The a metric.", Type: collector.HistogramType, Stability: collector.Stable, Buckets: []float64{0.5, 10, math.Inf(1)}})
+			Expect(out.String()).To(HaveSuffix("Buckets: 0.5, 10, +Inf.\n\n"))
+		})
+
+		It("should render the example sample", func() {
+			var out strings.Builder
+			example := &collector.Sample{Name: "kubevirt_a_seconds_sum", Labels: map[string]string{"node": "<redacted>"}, Value: "0.42"}
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a_seconds", Description: "The a metric.", Type: collector.HistogramType, Stability: collector.Stable, Example: example})
+			Expect(out.String()).To(HaveSuffix("Example: `kubevirt_a_seconds_sum{node=\"<redacted>\"} 0.42`.\n\n"))
+		})
+
+		It("should render the summary quantiles", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a_seconds", Description: "The a metric.", Type: collector.SummaryType, Stability: collector.Stable, Quantiles: []float64{0.5, 0.9, 0.99}})
+			Expect(out.String()).To(HaveSuffix("Quantiles: 0.5, 0.9, 0.99.\n\n"))
+		})
+
+		It("should render the metrics a recording rule is derived from", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_rule", Description: "A rule.", Type: collector.GaugeType, Stability: collector.Stable,
+				Source: "recording-rule", DerivedFrom: []string{"kubevirt_a", "kubevirt_b"}})
+			Expect(out.String()).To(HaveSuffix("Source: recording-rule.\nDerived from: `kubevirt_a`, `kubevirt_b`.\n\n"))
+		})
+
+		DescribeTable("should render the deprecation timeline after the stability", func(deprecatedIn, removedIn, expected string) {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Deprecated,
+				DeprecatedInVersion: deprecatedIn, RemovedInVersion: removedIn})
+			Expect(out.String()).To(HaveSuffix("Stability: DEPRECATED.\n" + expected + "\n"))
+		},
+			Entry("with both versions", "v1.2", "v1.4", "Deprecated in v1.2, scheduled for removal in v1.4.\n"),
+			Entry("with the deprecation version only", "v1.2", "", "Deprecated in v1.2.\n"),
+			Entry("with the removal version only", "", "v1.4", "Scheduled for removal in v1.4.\n"),
+			Entry("without versions", "", "", ""),
+		)
+
+		It("should link the file registering the metric when known", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable,
+				DefinedIn: "pkg/monitoring/a.go"})
+			Expect(out.String()).To(HaveSuffix("Stability: STABLE.\nDefined in: [`pkg/monitoring/a.go`](../pkg/monitoring/a.go).\n\n"))
+		})
+
+		It("should flag the high cardinality labels after the labels", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable,
+				Labels: []string{"name", "namespace", "node"}, HighCardinalityLabels: []string{"name", "node"}})
+			Expect(out.String()).To(HaveSuffix("Labels: `name`, `namespace`, `node`.\nHigh cardinality: `name`, `node`.\n\n"))
+		})
+
+		It("should render the stable id as an anchor before the heading", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable, ID: "a-metric"})
+			Expect(out.String()).To(HavePrefix("<a id=\"a-metric\"></a>\n### kubevirt_a\n"))
+		})
+	})
+
+	Context("deprecated metrics", func() {
+		It("should render with a badge", func() {
+			var out strings.Builder
+			writeMetric(&out, collector.Metric{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Deprecated})
+			Expect(out.String()).To(HavePrefix("### kubevirt_a\n**Deprecated** The a metric. Type: Gauge.\n"))
+		})
+
+		It("should be sorted after the other metrics of their group", func() {
+			metrics := collector.List{
+				{Name: "kubevirt_vmi_a", Stability: collector.Deprecated},
+				{Name: "kubevirt_vmi_c", Stability: collector.Stable},
+				{Name: "kubevirt_vmi_b", Stability: collector.Stable},
+			}
+			groups := groupByComponent(metrics, sortByName)
+			Expect(groups).To(HaveLen(1))
+
+			var names []string
+			for _, m := range groups[0].metrics {
+				names = append(names, m.Name)
+			}
+			Expect(names).To(Equal([]string{"kubevirt_vmi_b", "kubevirt_vmi_c", "kubevirt_vmi_a"}))
+		})
+	})
+
+	DescribeTable("escapeMarkdown", func(description, expected string) {
+		Expect(escapeMarkdown(description)).To(Equal(expected))
+	},
+		Entry("should escape pipes", "Either a | b.", `Either a \| b.`),
+		Entry("should escape asterisks", "Matches *all* VMIs.", `Matches \*all\* VMIs.`),
+		Entry("should preserve code spans", "The `phase|state` label.", "The `phase|state` label."),
+		Entry("should escape an unmatched backtick", "The `phase | state.", "The \\`phase \\| state."),
+	)
+
+	DescribeTable("sentence", func(description, expected string) {
+		Expect(sentence(description)).To(Equal(expected))
+	},
+		Entry("should keep a trailing period", "The a metric.", "The a metric."),
+		Entry("should add a missing period", "The a metric", "The a metric."),
+		Entry("should keep a trailing question mark", "Is it ready?", "Is it ready?"),
+		Entry("should keep a trailing exclamation mark", "Do not use!", "Do not use!"),
+		Entry("should add a period after a closing parenthesis", "The a metric (in bytes)", "The a metric (in bytes)."),
+		Entry("should trim trailing whitespace", "The a metric \n", "The a metric."),
+		Entry("should leave an empty description empty", "", ""),
+	)
+
+	DescribeTable("escapeTableCell", func(description, expected string) {
+		Expect(escapeTableCell(description)).To(Equal(expected))
+	},
+		Entry("should escape pipes", "Either a | b.", `Either a \| b.`),
+		Entry("should escape pipes inside code spans", "The `phase|state` label.", "The `phase\\|state` label."),
+		Entry("should join lines", "First line.\nSecond line.", "First line. Second line."),
+	)
+
+	It("writeTable should render the metrics sorted by name", func() {
+		metrics := collector.List{
+			{Name: "kubevirt_b", Type: collector.GaugeType, Description: "Either a | b."},
+			{Name: "kubevirt_a", Type: collector.CounterType, Description: "The a metric.", ID: "a-metric"},
+		}
+
+		var out strings.Builder
+		writeTable(&out, metrics, sortByName)
+		Expect(out.String()).To(Equal("## KubeVirt Metrics List\n" +
+			"| Name | Type | Description |\n" +
+			"|------|------|-------------|\n" +
+			"| `kubevirt_info` |  | Version information. |\n" +
+			"| <a id=\"a-metric\"></a>`kubevirt_a` | Counter | The a metric. |\n" +
+			"| `kubevirt_b` | Gauge | Either a \\| b. |\n\n"))
+	})
+
+	It("writeOpenMetricsMeta should only write the metadata of the metrics", func() {
+		metrics := collector.List{
+			{Name: "kubevirt_b_bytes", Description: "The \"b\" metric.", Type: collector.GaugeType, Unit: "bytes"},
+			{Name: "kubevirt_a_total", Description: "The a metric.", Type: collector.CounterType},
+		}
+
+		var out bytes.Buffer
+		Expect(writeOpenMetricsMeta(&out, metrics, sortByName)).To(Succeed())
+		Expect(out.String()).To(Equal("# HELP kubevirt_a The a metric.\n# TYPE kubevirt_a counter\n" +
+			"# HELP kubevirt_b_bytes The \\\"b\\\" metric.\n# TYPE kubevirt_b_bytes gauge\n# UNIT kubevirt_b_bytes bytes\n" +
+			"# EOF\n"))
+	})
+
+	It("writeCSV should write one quoted row per metric sorted by name", func() {
+		metrics := collector.List{
+			{Name: "kubevirt_b_bytes", Description: "The b metric, in bytes.", Type: collector.GaugeType, Unit: "bytes"},
+			{Name: "kubevirt_a_total", Description: "The \"a\" metric.", Type: collector.CounterType},
+		}
+
+		var out bytes.Buffer
+		Expect(writeCSV(&out, metrics, sortByName)).To(Succeed())
+		Expect(out.String()).To(Equal("name,type,unit,description\n" +
+			"kubevirt_a_total,Counter,,\"The \"\"a\"\" metric.\"\n" +
+			"kubevirt_b_bytes,Gauge,bytes,\"The b metric, in bytes.\"\n"))
+	})
+
+	It("writeJSON should include the histogram buckets and the summary quantiles", func() {
+		var out bytes.Buffer
+		Expect(writeJSON(&out, collector.List{
+			{Name: "kubevirt_a_seconds", Description: "The a metric.", Type: collector.HistogramType, Stability: collector.Stable, Buckets: []float64{0.5, 1, math.Inf(1)}},
+			{Name: "kubevirt_b_seconds", Description: "The b metric.", Type: collector.SummaryType, Stability: collector.Stable, Quantiles: []float64{0.5, 0.99}},
+			{Name: "kubevirt_c", Description: "The c metric.", Type: collector.GaugeType, Stability: collector.Stable},
+		}, sortByName)).To(Succeed())
+
+		var metrics []map[string]interface{}
+		Expect(json.Unmarshal(out.Bytes(), &metrics)).To(Succeed())
+		Expect(metrics).To(HaveLen(3))
+		Expect(metrics[0]).To(HaveKeyWithValue("buckets", []interface{}{0.5, 1.0}))
+		Expect(metrics[0]).ToNot(HaveKey("quantiles"))
+		Expect(metrics[1]).To(HaveKeyWithValue("quantiles", []interface{}{0.5, 0.99}))
+		Expect(metrics[1]).ToNot(HaveKey("buckets"))
+		Expect(metrics[2]).ToNot(HaveKey("buckets"))
+	})
+
+	Context("writeJSONSchema", func() {
+		var metric map[string]interface{}
+
+		BeforeEach(func() {
+			var out bytes.Buffer
+			Expect(writeJSONSchema(&out)).To(Succeed())
+
+			var schema map[string]interface{}
+			Expect(json.Unmarshal(out.Bytes(), &schema)).To(Succeed())
+			Expect(schema).To(HaveKeyWithValue("type", "array"))
+			Expect(schema).To(HaveKeyWithValue("items", map[string]interface{}{"$ref": "#/$defs/metric"}))
+			metric = schema["$defs"].(map[string]interface{})["metric"].(map[string]interface{})
+		})
+
+		It("should describe every field of the json output, requiring those which are always set", func() {
+			var fields, required []interface{}
+			jsonMetricType := reflect.TypeOf(jsonMetric{})
+			for i := 0; i < jsonMetricType.NumField(); i++ {
+				name, options, _ := strings.Cut(jsonMetricType.Field(i).Tag.Get("json"), ",")
+				fields = append(fields, name)
+				if options != "omitempty" {
+					required = append(required, name)
+				}
+			}
+
+			Expect(metric["properties"]).To(HaveLen(len(fields)))
+			for _, field := range fields {
+				Expect(metric["properties"]).To(HaveKey(field))
+			}
+			Expect(metric["required"]).To(Equal(required))
+		})
+
+		It("should enumerate the metric types", func() {
+			var metricTypes []interface{}
+			for _, t := range collector.MetricTypes() {
+				metricTypes = append(metricTypes, string(t))
+			}
+			typeSchema := metric["properties"].(map[string]interface{})["type"].(map[string]interface{})
+			Expect(typeSchema["enum"]).To(Equal(metricTypes))
+		})
+	})
+
+	Context("verify", func() {
+		metrics := collector.List{
+			{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable, ID: "a-metric"},
+			{Name: "kubevirt_b", Description: "The b metric.", Type: collector.GaugeType, Stability: collector.Stable, Source: "virt-controller"},
+			{Name: "kubevirt_rule", Description: "A rule.", Type: collector.GaugeType, Stability: collector.Stable, Source: "recording-rule"},
+		}
+
+		It("documentedMetrics should find the metrics and the recording rules of the generated markdown", func() {
+			var out bytes.Buffer
+			alerts := []collector.Alert{{Name: "KubeVirtAlert", Summary: "An alert.", Expr: "kubevirt_a > 0"}}
+			Expect(render(&out, metrics, renderOptions{format: formatMarkdown, layout: layoutHeadings, toc: true, summary: true, alerts: alerts})).To(Succeed())
+
+			documented, recordingRules, err := documentedMetrics(&out)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(documented).To(Equal(map[string]bool{"kubevirt_info": true, "kubevirt_a": true, "kubevirt_b": true, "kubevirt_rule": true}))
+			Expect(recordingRules).To(Equal(map[string]bool{"kubevirt_rule": true}))
+		})
+
+		It("documentedMetrics should reject the table layout", func() {
+			var out bytes.Buffer
+			Expect(render(&out, metrics, renderOptions{format: formatMarkdown, layout: layoutTable})).To(Succeed())
+
+			_, _, err := documentedMetrics(&out)
+			Expect(err).To(MatchError("verify mode only supports the headings layout, the file is in the table layout"))
+		})
+
+		It("compareExposed should report the drift in both directions, ignoring the recording rules", func() {
+			documented := map[string]bool{"kubevirt_a": true, "kubevirt_b": true, "kubevirt_rule": true}
+			recordingRules := map[string]bool{"kubevirt_rule": true}
+			scraped := collector.List{{Name: "kubevirt_a"}, {Name: "kubevirt_c"}}
+
+			report := compareExposed(documented, recordingRules, scraped)
+			Expect(report.empty()).To(BeFalse())
+
+			var out strings.Builder
+			report.write(&out, "metrics.md")
+			Expect(out.String()).To(Equal("documented in metrics.md but not exposed by the endpoints:\n  kubevirt_b\n" +
+				"exposed by the endpoints but not documented in metrics.md:\n  kubevirt_c\n"))
+		})
+
+		It("compareExposed should report nothing when in sync", func() {
+			report := compareExposed(map[string]bool{"kubevirt_a": true}, nil, collector.List{{Name: "kubevirt_a"}})
+			Expect(report.empty()).To(BeTrue())
+		})
+	})
+
+	Context("alerts", func() {
+		metrics := collector.List{{Name: "kubevirt_a", Description: "The a metric.", Type: collector.GaugeType, Stability: collector.Stable}}
+		alerts := []collector.Alert{
+			{Name: "KubeVirtA", Severity: "warning", Summary: "A is *down*", Expr: "kubevirt_a == 0"},
+			{Name: "KubeVirtB", Summary: "B is broken.", Expr: "kubevirt_b > 0"},
+		}
+
+		It("writeAlerts should render the name, summary, severity and expression of each alert", func() {
+			var out strings.Builder
+			writeAlerts(&out, alerts)
+			Expect(out.String()).To(Equal("## Alerts\n" +
+				"### KubeVirtA\nA is \\*down\\*. Severity: warning.\n\n```promql\nkubevirt_a == 0\n```\n\n" +
+				"### KubeVirtB\nB is broken.\n\n```promql\nkubevirt_b > 0\n```\n\n"))
+		})
+
+		It("should only be rendered when included", func() {
+			var without, with bytes.Buffer
+			Expect(render(&without, metrics, renderOptions{format: formatMarkdown, layout: layoutHeadings, toc: true})).To(Succeed())
+			Expect(render(&with, metrics, renderOptions{format: formatMarkdown, layout: layoutHeadings, toc: true, alerts: alerts})).To(Succeed())
+
+			Expect(without.String()).ToNot(ContainSubstring(alertsHeading))
+			Expect(with.String()).To(ContainSubstring(alertsHeading + "\n### KubeVirtA\n"))
+			Expect(with.String()).To(ContainSubstring("- [Alerts](#alerts)\n  - [KubeVirtA](#kubevirta)\n"))
+			Expect(strings.Index(with.String(), "### kubevirt_a")).To(BeNumerically("<", strings.Index(with.String(), alertsHeading)))
+		})
+	})
+
+	Context("unifiedDiff", func() {
+		It("should be empty for equal contents", func() {
+			Expect(unifiedDiff("a", "b", "line\n", "line\n")).To(BeEmpty())
+		})
+
+		It("should only include the changed lines and their context", func() {
+			before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+			after := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+			Expect(unifiedDiff("a", "b", before, after)).To(Equal("--- a\n+++ b\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"))
+		})
+	})
+
+	DescribeTable("lintMetrics", func(m collector.Metric, expected []string) {
+		Expect(lintMetrics(collector.List{m}, []string{collector.DefaultPrefix})).To(Equal(expected))
+	},
+		Entry("should accept a conforming counter", collector.Metric{Name: "kubevirt_vmi_migrations_total", Type: collector.CounterType}, nil),
+		Entry("should accept a timestamp counter", collector.Metric{Name: "kubevirt_vm_running_status_last_transition_timestamp_seconds", Type: collector.CounterType}, nil),
+		Entry("should accept a conforming histogram", collector.Metric{Name: "kubevirt_vmi_phase_transition_time_seconds", Type: collector.HistogramType}, nil),
+		Entry("should flag a counter without _total", collector.Metric{Name: "kubevirt_vmi_migrations", Type: collector.CounterType},
+			[]string{"kubevirt_vmi_migrations: counter name must end with one of: _total, _timestamp_seconds"}),
+		Entry("should flag a histogram without a unit suffix", collector.Metric{Name: "kubevirt_vmi_phase_transition_time", Type: collector.HistogramType},
+			[]string{"kubevirt_vmi_phase_transition_time: histogram name must end with a unit suffix, one of: _seconds, _bytes, _ratio"}),
+		Entry("should flag uppercase characters", collector.Metric{Name: "kubevirt_vmi_Memory_bytes", Type: collector.GaugeType},
+			[]string{"kubevirt_vmi_Memory_bytes: name must be snake_case, without uppercase characters"}),
+		Entry("should flag a name without the prefix", collector.Metric{Name: "vmi_memory_bytes", Type: collector.GaugeType},
+			[]string{"vmi_memory_bytes: name must start with one of: kubevirt_"}),
+	)
+
+	DescribeTable("lintDescriptions", func(description string, maxLength int, expected []string) {
+		Expect(lintDescriptions(collector.List{{Name: "kubevirt_a", Description: description}}, maxLength)).To(Equal(expected))
+	},
+		Entry("should accept a short sentence", "The a metric.", 20, nil),
+		Entry("should accept a description of the maximum length", strings.Repeat("a", 19)+".", 20, nil),
+		Entry("should accept questions and exclamations", "Is it the a metric?", 20, nil),
+		Entry("should ignore the trailing whitespace", "The a metric. \n", 13, nil),
+		Entry("should flag an over-long description", strings.Repeat("a", 20)+".", 20,
+			[]string{"kubevirt_a: description is 21 characters long, longer than the maximum of 20"}),
+		Entry("should count the characters rather than the bytes", strings.Repeat("é", 19)+".", 20, nil),
+		Entry("should not limit the length when the maximum is zero", strings.Repeat("a", 400)+".", 0, nil),
+		Entry("should flag a description missing a period", "The a metric", 20,
+			[]string{"kubevirt_a: description must end with punctuation, one of: . ! ?"}),
+		Entry("should skip a missing description", "", 20, nil),
+	)
+
+	It("validateMetrics should accept the collected KubeVirt metrics", func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		f := registerMetricsFlags(fs)
+		Expect(fs.Parse(nil)).To(Succeed())
+		Expect(validateMetrics(f.collectMetrics(quietLevel), f.prefixes())).To(BeEmpty())
+	})
+
+	DescribeTable("validateMetrics", func(metrics collector.List, expected []string) {
+		Expect(validateMetrics(metrics, []string{collector.DefaultPrefix})).To(Equal(expected))
+	},
+		Entry("should accept conforming metrics", collector.List{
+			{Name: "kubevirt_vmi_migrations_total", Type: collector.CounterType},
+			{Name: "kubevirt_vmi_memory_bytes", Type: collector.GaugeType},
+		}, nil),
+		Entry("should include the naming convention violations", collector.List{{Name: "kubevirt_vmi_migrations", Type: collector.CounterType}},
+			[]string{"kubevirt_vmi_migrations: counter name must end with one of: _total, _timestamp_seconds"}),
+		Entry("should flag a metric without a type", collector.List{{Name: "kubevirt_vmi_memory_bytes"}},
+			[]string{"kubevirt_vmi_memory_bytes: type is missing"}),
+	)
+
+	Context("environment", func() {
+		var (
+			fs        *flag.FlagSet
+			format    *string
+			timeout   *time.Duration
+			endpoints endpointList
+		)
+
+		lookup := func(env map[string]string) func(string) (string, bool) {
+			return func(name string) (string, bool) {
+				value, ok := env[name]
+				return value, ok
+			}
+		}
+
+		BeforeEach(func() {
+			fs = flag.NewFlagSet("test", flag.ContinueOnError)
+			format = fs.String("format", formatMarkdown, "")
+			timeout = fs.Duration("timeout", time.Second, "")
+			endpoints = nil
+			fs.Var(&endpoints, "endpoint", "")
+		})
+
+		It("should keep the defaults without flags or environment variables", func() {
+			Expect(fs.Parse(nil)).To(Succeed())
+			Expect(applyEnvironment(fs, lookup(nil))).To(Succeed())
+			Expect(*format).To(Equal(formatMarkdown))
+			Expect(*timeout).To(Equal(time.Second))
+		})
+
+		It("should override the defaults with the environment variables", func() {
+			Expect(fs.Parse(nil)).To(Succeed())
+			Expect(applyEnvironment(fs, lookup(map[string]string{"DOCGEN_FORMAT": formatJSON, "DOCGEN_TIMEOUT": "5s"}))).To(Succeed())
+			Expect(*format).To(Equal(formatJSON))
+			Expect(*timeout).To(Equal(5 * time.Second))
+		})
+
+		It("should override the environment variables with the explicit flags", func() {
+			Expect(fs.Parse([]string{"-format=csv"})).To(Succeed())
+			Expect(applyEnvironment(fs, lookup(map[string]string{"DOCGEN_FORMAT": formatJSON, "DOCGEN_TIMEOUT": "5s"}))).To(Succeed())
+			Expect(*format).To(Equal(formatCSV))
+			Expect(*timeout).To(Equal(5 * time.Second))
+		})
+
+		It("should split the environment variable of a repeatable flag", func() {
+			Expect(fs.Parse(nil)).To(Succeed())
+			Expect(applyEnvironment(fs, lookup(map[string]string{"DOCGEN_ENDPOINT": "http://a/metrics,http://b/metrics"}))).To(Succeed())
+			Expect(endpoints).To(Equal(endpointList{"http://a/metrics", "http://b/metrics"}))
+		})
+
+		It("should fail on an invalid environment variable value", func() {
+			Expect(fs.Parse(nil)).To(Succeed())
+			err := applyEnvironment(fs, lookup(map[string]string{"DOCGEN_TIMEOUT": "soon"}))
+			Expect(err).To(MatchError(ContainSubstring(`invalid value "soon" of DOCGEN_TIMEOUT for flag -timeout`)))
+		})
+
+		It("should derive the environment variable names from the flag names", func() {
+			Expect(envName("rules-namespace")).To(Equal("DOCGEN_RULES_NAMESPACE"))
+		})
+	})
+
+	Context("config", func() {
+		writeConfig := func(content string) string {
+			path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+			return path
+		}
+
+		It("should load the prefixes, exclude, allowlist and overrides", func() {
+			config, err := loadConfig(writeConfig("prefixes: [kubevirt_, cdi_]\nexclude: [kubevirt_b]\nallowlist: [kubevirt_a]\noverrides:\n  kubevirt_a: The a metric.\n"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(&metricsConfig{
+				Prefixes:  []string{"kubevirt_", "cdi_"},
+				Exclude:   []string{"kubevirt_b"},
+				Allowlist: []string{"kubevirt_a"},
+				Overrides: map[string]string{"kubevirt_a": "The a metric."},
+			}))
+		})
+
+		It("should fail on unknown settings", func() {
+			path := writeConfig("prefix: kubevirt_\n")
+			_, err := loadConfig(path)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse the config " + path)))
+		})
+
+		Context("precedence", func() {
+			config := &metricsConfig{
+				Prefixes:  []string{"kubevirt_", "cdi_"},
+				Exclude:   []string{"kubevirt_b", "kubevirt_c"},
+				Allowlist: []string{"kubevirt_a"},
+				Overrides: map[string]string{"kubevirt_a": "The a metric."},
+			}
+
+			parse := func(args []string, env map[string]string) *metricsFlags {
+				fs := flag.NewFlagSet("test", flag.ContinueOnError)
+				f := registerMetricsFlags(fs)
+				Expect(fs.Parse(args)).To(Succeed())
+				Expect(applyEnvironment(fs, func(name string) (string, bool) {
+					value, ok := env[name]
+					return value, ok
+				})).To(Succeed())
+				f.applyConfig(fs, config)
+				return f
+			}
+
+			It("should override the defaults with the config", func() {
+				f := parse(nil, nil)
+				Expect(f.prefixes()).To(Equal([]string{"kubevirt_", "cdi_"}))
+				Expect(*f.exclude).To(Equal("kubevirt_b,kubevirt_c"))
+				Expect(f.allowedNames).To(Equal([]string{"kubevirt_a"}))
+				Expect(f.descriptionOverrides).To(Equal(map[string]string{"kubevirt_a": "The a metric."}))
+			})
+
+			It("should override the config with the flags", func() {
+				f := parse([]string{"-prefix=kubevirt_", "-exclude=kubevirt_d", "-allowlist=allowlist.txt", "-overrides=overrides.yaml"}, nil)
+				Expect(f.prefixes()).To(Equal([]string{"kubevirt_"}))
+				Expect(*f.exclude).To(Equal("kubevirt_d"))
+				Expect(f.allowedNames).To(BeNil())
+				Expect(f.descriptionOverrides).To(BeNil())
+			})
+
+			It("should override the config with the environment variables", func() {
+				f := parse(nil, map[string]string{"DOCGEN_PREFIX": "cdi_"})
+				Expect(f.prefixes()).To(Equal([]string{"cdi_"}))
+				Expect(*f.exclude).To(Equal("kubevirt_b,kubevirt_c"))
+			})
+		})
+	})
+
+	DescribeTable("splitCommand", func(args []string, expectedCommand string, expectedArgs []string) {
+		command, commandArgs := splitCommand(args)
+		Expect(command).To(Equal(expectedCommand))
+		Expect(commandArgs).To(Equal(expectedArgs))
+	},
+		Entry("should default to generate without arguments", []string{}, generateCommand, []string{}),
+		Entry("should default to generate when the first argument is a flag", []string{"-format=json"}, generateCommand, []string{"-format=json"}),
+		Entry("should pass the arguments following the command", []string{"validate", "-prefix=kubevirt_"}, validateCommand, []string{"-prefix=kubevirt_"}),
+		Entry("should return unknown commands for main to reject", []string{"render"}, "render", []string{}),
+	)
+})
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// excludeMetrics removes the metrics matching any of the names or glob patterns, failing
+// if a pattern doesn't match any metric so the exclusions don't go stale
+func excludeMetrics(m collector.List, patterns []string) (collector.List, error) {
+	matched := make(map[string]bool, len(patterns))
+	var kept collector.List
+	for _, met := range m {
+		excluded := false
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, met.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern %q: %v", pattern, err)
+			}
+			if ok {
+				matched[pattern], excluded = true, true
+			}
+		}
+		if !excluded {
+			kept = append(kept, met)
+		}
+	}
+
+	var unmatched []string
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("excluded metrics not found: %s", strings.Join(unmatched, ", "))
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// endpointList is a repeatable flag collecting the metrics endpoints to document
+type endpointList []string
+
+// String implements flag.Value.String
+func (e *endpointList) String() string {
+	return strings.Join(*e, ",")
+}
+
+// Set implements flag.Value.Set
+func (e *endpointList) Set(endpoint string) error {
+	*e = append(*e, endpoint)
+	return nil
+}
+
+// envPrefix is the prefix of the environment variables the flags fall back to
+const envPrefix = "DOCGEN_"
+
+// envName returns the environment variable of the flag, e.g. DOCGEN_RULES_NAMESPACE for -rules-namespace
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvironment sets the flags not passed on the command line from their environment variables,
+// so explicit flags take precedence over the environment, which takes precedence over the defaults.
+// The environment variable of a repeatable flag is a comma separated list of its values
+func applyEnvironment(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || passed[f.Name] {
+			return
+		}
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		values := []string{value}
+		if _, repeatable := f.Value.(*endpointList); repeatable {
+			values = strings.Split(value, ",")
+		}
+		for _, v := range values {
+			if setErr := fs.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid value %q of %s for flag -%s: %w", value, envName(f.Name), f.Name, setErr)
+				return
+			}
+		}
+	})
+	return err
+}
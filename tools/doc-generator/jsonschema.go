@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const formatJSONSchema = "jsonschema"
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+type jsonSchema map[string]interface{}
+
+// writeJSONSchema writes the JSON Schema of the output of the json format, the metric types
+// and stability levels being enumerated
+func writeJSONSchema(w io.Writer) error {
+	stringSchema := func(description string) jsonSchema {
+		return jsonSchema{"type": "string", "description": description}
+	}
+
+	metricTypes := collector.MetricTypes()
+	stabilities := collector.StabilityLevels()
+	metric := jsonSchema{
+		"type": "object",
+		"properties": jsonSchema{
+			"name":        stringSchema("Name of the metric"),
+			"description": stringSchema("Description of the metric"),
+			"type":        jsonSchema{"type": "string", "description": "Type of the metric", "enum": metricTypes},
+			"unit":        stringSchema("Unit of the metric, declared by its OpenMetrics UNIT line"),
+			"labels": jsonSchema{
+				"type":        "array",
+				"description": "Names of the labels of the metric, sorted",
+				"items":       jsonSchema{"type": "string"},
+			},
+			"stability":  jsonSchema{"type": "string", "description": "Stability level of the metric", "enum": stabilities},
+			"source":     stringSchema("Component exposing the metric, or recording-rule"),
+			"overridden": jsonSchema{"type": "boolean", "description": "Whether the description was replaced by an override"},
+			"id":         stringSchema("Stable ID of the metric, surviving renames"),
+			"derivedFrom": jsonSchema{
+				"type":        "array",
+				"description": "Names of the documented metrics the expression of a recording rule refers to, sorted",
+				"items":       jsonSchema{"type": "string"},
+			},
+			"deprecatedIn": stringSchema("Version the metric was deprecated in"),
+			"removedIn":    stringSchema("Version the metric is scheduled to be removed in"),
+			"definedIn":    stringSchema("Repository relative path of the file registering the metric"),
+			"buckets": jsonSchema{
+				"type":        "array",
+				"description": "Upper bounds of the buckets of a histogram, ascending, without the +Inf bucket every histogram has",
+				"items":       jsonSchema{"type": "number"},
+			},
+			"quantiles": jsonSchema{
+				"type":        "array",
+				"description": "Quantiles of a summary, ascending",
+				"items":       jsonSchema{"type": "number"},
+			},
+			"example": stringSchema("Example sample of the metric as an exposition line, the label values looking like identifiers redacted"),
+			"highCardinalityLabels": jsonSchema{
+				"type":        "array",
+				"description": "Labels of the metric taking a value per node, VMI or pod, sorted",
+				"items":       jsonSchema{"type": "string"},
+			},
+		},
+		"required":             []string{"name", "description", "type", "stability"},
+		"additionalProperties": false,
+	}
+
+	schema := jsonSchema{
+		"$schema":     jsonSchemaDialect,
+		"title":       "KubeVirt metrics",
+		"description": "Catalog of the KubeVirt metrics, as written by doc-generator -format=json",
+		"type":        "array",
+		"items":       jsonSchema{"$ref": "#/$defs/metric"},
+		"$defs":       jsonSchema{"metric": metric},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
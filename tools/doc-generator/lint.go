@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// counterSuffixes are the suffixes counter names must end with, _timestamp_seconds for the counters
+// of the times something last happened
+var counterSuffixes = []string{"_total", "_timestamp_seconds"}
+
+// histogramUnitSuffixes are the base unit suffixes histogram names must end with
+var histogramUnitSuffixes = []string{"_seconds", "_bytes", "_ratio"}
+
+var snakeCaseName = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// lintMetrics checks the metric names against the KubeVirt metrics naming conventions
+// and returns a description of each violation
+func lintMetrics(metrics collector.List, prefixes []string) []string {
+	var violations []string
+	for _, m := range metrics {
+		if !snakeCaseName.MatchString(m.Name) {
+			violations = append(violations, fmt.Sprintf("%s: name must be snake_case, without uppercase characters", m.Name))
+		}
+		if !collector.HasAnyPrefix(m.Name, prefixes) {
+			violations = append(violations, fmt.Sprintf("%s: name must start with one of: %s", m.Name, strings.Join(prefixes, ", ")))
+		}
+
+		switch m.Type {
+		case collector.CounterType:
+			if !hasAnySuffix(m.Name, counterSuffixes) {
+				violations = append(violations, fmt.Sprintf("%s: counter name must end with one of: %s", m.Name, strings.Join(counterSuffixes, ", ")))
+			}
+		case collector.HistogramType:
+			if !hasAnySuffix(m.Name, histogramUnitSuffixes) {
+				violations = append(violations, fmt.Sprintf("%s: histogram name must end with a unit suffix, one of: %s", m.Name, strings.Join(histogramUnitSuffixes, ", ")))
+			}
+		}
+	}
+	return violations
+}
+
+// defaultMaxDescriptionLength is the default maximum number of characters of a metric description
+const defaultMaxDescriptionLength = 300
+
+// sentenceEndings are the punctuation marks a description must end with
+var sentenceEndings = []string{".", "!", "?"}
+
+// lintDescriptions checks the descriptions against the style guide, i.e. at most maxLength characters,
+// unless maxLength is zero or less, and ending with punctuation. Metrics without a description are skipped,
+// undescribedError reports them
+func lintDescriptions(metrics collector.List, maxLength int) []string {
+	var violations []string
+	for _, m := range metrics {
+		if !hasDescription(m) {
+			continue
+		}
+		description := strings.TrimRightFunc(m.Description, unicode.IsSpace)
+		if length := utf8.RuneCountInString(description); maxLength > 0 && length > maxLength {
+			violations = append(violations, fmt.Sprintf("%s: description is %d characters long, longer than the maximum of %d", m.Name, length, maxLength))
+		}
+		if !hasAnySuffix(description, sentenceEndings) {
+			violations = append(violations, fmt.Sprintf("%s: description must end with punctuation, one of: %s", m.Name, strings.Join(sentenceEndings, " ")))
+		}
+	}
+	return violations
+}
+
+// validateMetrics returns the naming convention violations of lintMetrics along with a description
+// of each metric without a type. Duplicated names need no check, the collected metrics are deduplicated
+func validateMetrics(metrics collector.List, prefixes []string) []string {
+	violations := lintMetrics(metrics, prefixes)
+	for _, m := range metrics {
+		if m.Type == "" {
+			violations = append(violations, fmt.Sprintf("%s: type is missing", m.Name))
+		}
+	}
+	return violations
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// logLevel controls the diagnostics written to stderr, errors are always written
+type logLevel int
+
+const (
+	quietLevel logLevel = iota
+	defaultLevel
+	verboseLevel
+)
+
+func newLogLevel(quiet, verbose bool) (logLevel, error) {
+	switch {
+	case quiet && verbose:
+		return defaultLevel, fmt.Errorf("-quiet and -verbose are mutually exclusive")
+	case quiet:
+		return quietLevel, nil
+	case verbose:
+		return verboseLevel, nil
+	}
+	return defaultLevel, nil
+}
+
+// warnings returns the writer of the warnings, which are discarded at the quiet level
+func (l logLevel) warnings() io.Writer {
+	if l == quietLevel {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// verbose returns the writer of the details only written at the verbose level, nil otherwise
+func (l logLevel) verbose() io.Writer {
+	if l == verboseLevel {
+		return os.Stderr
+	}
+	return nil
+}
+
+// logRemoved writes the names of the metrics removed from the list at the verbose level
+func (l logLevel) logRemoved(reason string, before, after collector.List) {
+	if l != verboseLevel {
+		return
+	}
+
+	kept := make(map[string]bool, len(after))
+	for _, m := range after {
+		kept[m.Name] = true
+	}
+	var removed []string
+	for _, m := range before {
+		if !kept[m.Name] {
+			removed = append(removed, m.Name)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s %d metrics: %s\n", reason, len(removed), strings.Join(removed, ", "))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// deprecatedBadge prefixes the description of deprecated metrics
+const deprecatedBadge = "**Deprecated** "
+
+// sentence terminates the text with a period unless it already ends with a punctuation mark
+// terminating a sentence, so the clauses following it read cleanly
+func sentence(text string) string {
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	if text == "" || hasAnySuffix(text, sentenceEndings) {
+		return text
+	}
+	return text + "."
+}
+
+// idAnchor returns the HTML anchor deep-linking to the documentation of a metric by its stable ID
+func idAnchor(id string) string {
+	return fmt.Sprintf(`<a id="%s"></a>`, html.EscapeString(id))
+}
+
+// markdownEscaper escapes the characters that would otherwise be interpreted as markdown formatting
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`*`, `\*`,
+	`_`, `\_`,
+	`|`, `\|`,
+	`<`, `\<`,
+)
+
+// escapeMarkdown escapes markdown formatting characters of the text while
+// preserving intentional code spans delimited by backticks
+func escapeMarkdown(text string) string {
+	parts := strings.Split(text, "`")
+	// with an odd number of backticks the last one doesn't close a code span
+	unmatched := len(parts)%2 == 0
+
+	var escaped strings.Builder
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			escaped.WriteString(markdownEscaper.Replace(part))
+		case unmatched && i == len(parts)-1:
+			escaped.WriteString("\\`" + markdownEscaper.Replace(part))
+		default:
+			escaped.WriteString("`" + part + "`")
+		}
+	}
+	return escaped.String()
+}
+
+// escapeTableCell escapes the text for a markdown table cell, where pipes must
+// be escaped even inside code spans and line breaks would end the row
+func escapeTableCell(text string) string {
+	parts := strings.Split(escapeMarkdown(text), `\|`)
+	for i := range parts {
+		parts[i] = strings.ReplaceAll(parts[i], "|", `\|`)
+	}
+	return strings.ReplaceAll(strings.Join(parts, `\|`), "\n", " ")
+}
+
+// formatBuckets renders the bucket boundaries, or the summary quantiles, in ascending order, the +Inf bucket being last
+func formatBuckets(buckets []float64) string {
+	formatted := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		if math.IsInf(bucket, 1) {
+			formatted = append(formatted, "+Inf")
+		} else {
+			formatted = append(formatted, strconv.FormatFloat(bucket, 'g', -1, 64))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// deprecationNote describes the deprecation timeline of the metric, empty when unknown
+func deprecationNote(m collector.Metric) string {
+	switch {
+	case m.DeprecatedInVersion != "" && m.RemovedInVersion != "":
+		return fmt.Sprintf("Deprecated in %s, scheduled for removal in %s.", m.DeprecatedInVersion, m.RemovedInVersion)
+	case m.DeprecatedInVersion != "":
+		return fmt.Sprintf("Deprecated in %s.", m.DeprecatedInVersion)
+	case m.RemovedInVersion != "":
+		return fmt.Sprintf("Scheduled for removal in %s.", m.RemovedInVersion)
+	default:
+		return ""
+	}
+}
+
+// sourceLinkBase leads from the documentation, generated into the docs directory, to the repository root
+const sourceLinkBase = "../"
+
+// sourceLink renders a link to the file at the repository relative path
+func sourceLink(path string) string {
+	return fmt.Sprintf("[`%s`](%s%s)", path, sourceLinkBase, path)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const formatOpenMetricsMeta = "openmetrics-meta"
+
+// openMetricsHelpEscaper escapes HELP values as required by the OpenMetrics text format
+var openMetricsHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// writeOpenMetricsMeta writes the HELP, TYPE and UNIT metadata of the metrics, in the sort order,
+// as an OpenMetrics exposition without samples
+func writeOpenMetricsMeta(w io.Writer, metrics collector.List, order string) error {
+	sorted := sortMetrics(metrics, order)
+
+	for _, m := range sorted {
+		family := openMetricsFamily(m)
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family, openMetricsHelpEscaper.Replace(m.Description), family, openMetricsType(m.Type)); err != nil {
+			return err
+		}
+		if m.Unit != "" {
+			if _, err := fmt.Fprintf(w, "# UNIT %s %s\n", family, m.Unit); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "# EOF")
+	return err
+}
+
+// openMetricsFamily returns the metric family name, OpenMetrics counter families don't include the _total suffix of their samples
+func openMetricsFamily(m collector.Metric) string {
+	if m.Type == collector.CounterType {
+		return strings.TrimSuffix(m.Name, "_total")
+	}
+	return m.Name
+}
+
+func openMetricsType(mType collector.MetricType) string {
+	switch mType {
+	case collector.CounterType, collector.GaugeType, collector.HistogramType, collector.SummaryType:
+		return strings.ToLower(string(mType))
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// the orders of the metrics in the output, by name being the default one
+const (
+	sortByName = "name"
+	sortByType = "type"
+)
+
+func validateSortOrder(order string) error {
+	if order != "" && order != sortByName && order != sortByType {
+		return fmt.Errorf("unsupported sort order %q", order)
+	}
+	return nil
+}
+
+// sortMetrics returns a copy of the metrics sorted in the requested order, by name when empty
+func sortMetrics(metrics collector.List, order string) collector.List {
+	sorted := make(collector.List, len(metrics))
+	copy(sorted, metrics)
+	if order == sortByType {
+		sort.Sort(collector.ByType(sorted))
+	} else {
+		sort.Sort(sorted)
+	}
+	return sorted
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const (
+	layoutHeadings = "headings"
+	layoutTable    = "table"
+)
+
+const tableHeader = "| Name | Type | Description |\n" +
+	"|------|------|-------------|\n"
+
+// writeTable writes all the metrics, in the sort order, as a single markdown table
+func writeTable(w io.Writer, metrics collector.List, order string) {
+	sorted := sortMetrics(metrics, order)
+
+	fmt.Fprint(w, "## KubeVirt Metrics List\n")
+	fmt.Fprint(w, tableHeader)
+	fmt.Fprintln(w, tableRow("kubevirt_info", "", "Version information."))
+	for _, m := range sorted {
+		description := missingDescription
+		if hasDescription(m) {
+			description = escapeTableCell(m.Description)
+		}
+		if m.Deprecated() {
+			description = deprecatedBadge + description
+		}
+		row := tableRow(m.Name, m.Type, description)
+		if m.ID != "" {
+			row = strings.Replace(row, "| ", "| "+idAnchor(m.ID), 1)
+		}
+		fmt.Fprintln(w, row)
+	}
+	fmt.Fprintln(w)
+}
+
+func tableRow(name string, mType collector.MetricType, description string) string {
+	return fmt.Sprintf("| `%s` | %s | %s |", name, mType, description)
+}
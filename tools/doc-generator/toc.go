@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const tocHeading = "## Table of Contents\n"
+
+// writeTOC writes a table of contents linking to the headings of each component and metric,
+// followed by the ones of the alerts if any are documented
+func writeTOC(w io.Writer, groups []metricGroup, alerts []collector.Alert) {
+	fmt.Fprint(w, tocHeading)
+	fmt.Fprintln(w, tocLink("", "kubevirt_info"))
+	for _, group := range groups {
+		fmt.Fprintln(w, tocLink("", group.component))
+		for _, m := range group.metrics {
+			fmt.Fprintln(w, tocLink("  ", m.Name))
+		}
+	}
+	if alerts != nil {
+		fmt.Fprintln(w, tocLink("", strings.TrimPrefix(alertsHeading, "## ")))
+		for _, alert := range alerts {
+			fmt.Fprintln(w, tocLink("  ", alert.Name))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func tocLink(indent string, heading string) string {
+	return fmt.Sprintf("%s- [%s](#%s)", indent, heading, headingAnchor(heading))
+}
+
+// headingAnchor returns the anchor GitHub generates for a markdown heading: the heading is
+// lowercased, spaces are replaced by dashes and any other punctuation is dropped
+func headingAnchor(heading string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case r == ' ':
+			anchor.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			anchor.WriteRune(r)
+		}
+	}
+	return anchor.String()
+}
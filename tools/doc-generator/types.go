@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+// parseMetricTypeNames normalizes a list of type names, e.g. the value of the -types flag
+func parseMetricTypeNames(names []string) ([]collector.MetricType, error) {
+	types := make([]collector.MetricType, 0, len(names))
+	for _, name := range names {
+		t, err := collector.ParseMetricTypeName(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// filterTypes keeps only the metrics of the given types
+func filterTypes(m collector.List, types []collector.MetricType) collector.List {
+	var kept collector.List
+	for _, met := range m {
+		if slices.Contains(types, met.Type) {
+			kept = append(kept, met)
+		}
+	}
+	return kept
+}
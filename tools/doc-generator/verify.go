@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"kubevirt.io/kubevirt/tools/doc-generator/collector"
+)
+
+const recordingRuleSourceLine = "Source: recording-rule."
+
+// tableHeaderRow is the first line of the metrics table of the table layout
+var tableHeaderRow = strings.SplitN(tableHeader, "\n", 2)[0]
+
+// documentedMetrics returns the names of the metrics documented in a generated markdown file, together
+// with the recording rules, which aren't exposed by the endpoints. Only the headings layout is supported,
+// the table one doesn't document the sources the recording rules are told apart by
+func documentedMetrics(r io.Reader) (metrics map[string]bool, recordingRules map[string]bool, err error) {
+	metrics, recordingRules = map[string]bool{}, map[string]bool{}
+
+	var current string
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := scan.Text()
+		if line == alertsHeading {
+			// the headings of the alerts section are no metrics
+			break
+		}
+		switch {
+		case line == tableHeaderRow:
+			return nil, nil, fmt.Errorf("verify mode only supports the %s layout, the file is in the %s layout", layoutHeadings, layoutTable)
+		case strings.HasPrefix(line, "### "):
+			current = strings.TrimPrefix(line, "### ")
+			metrics[current] = true
+		case current != "" && strings.Contains(line, recordingRuleSourceLine):
+			recordingRules[current] = true
+		case line == "":
+			current = ""
+		}
+	}
+	return metrics, recordingRules, scan.Err()
+}
+
+// verifyReport lists the documented metrics the endpoints don't expose and the exposed metrics which aren't documented
+type verifyReport struct {
+	missingFromEndpoint []string
+	missingFromDoc      []string
+}
+
+func (r verifyReport) empty() bool {
+	return len(r.missingFromEndpoint) == 0 && len(r.missingFromDoc) == 0
+}
+
+func (r verifyReport) write(w io.Writer, fileName string) {
+	if len(r.missingFromEndpoint) > 0 {
+		fmt.Fprintf(w, "documented in %s but not exposed by the endpoints:\n", fileName)
+		for _, name := range r.missingFromEndpoint {
+			fmt.Fprintln(w, "  "+name)
+		}
+	}
+	if len(r.missingFromDoc) > 0 {
+		fmt.Fprintf(w, "exposed by the endpoints but not documented in %s:\n", fileName)
+		for _, name := range r.missingFromDoc {
+			fmt.Fprintln(w, "  "+name)
+		}
+	}
+}
+
+// compareExposed compares the documented metrics with the scraped ones, recording rules are evaluated
+// by Prometheus rather than exposed by the endpoints and thus only need to be documented
+func compareExposed(documented, recordingRules map[string]bool, scraped collector.List) verifyReport {
+	exposed := make(map[string]bool, len(scraped))
+	var report verifyReport
+	for _, m := range scraped {
+		exposed[m.Name] = true
+		if !documented[m.Name] {
+			report.missingFromDoc = append(report.missingFromDoc, m.Name)
+		}
+	}
+	for name := range documented {
+		if !exposed[name] && !recordingRules[name] {
+			report.missingFromEndpoint = append(report.missingFromEndpoint, name)
+		}
+	}
+	sort.Strings(report.missingFromEndpoint)
+	sort.Strings(report.missingFromDoc)
+	return report
+}
+
+// verifyFile scrapes the live endpoints and exits with a non-zero code, printing the drift,
+// if the exposed metrics don't match the ones documented in the output file
+func verifyFile(opts collector.Options, format string, output string) {
+	if len(opts.Endpoints) == 0 {
+		exitOnError(fmt.Errorf("verify mode requires at least one -endpoint"))
+	}
+	if format != formatMarkdown {
+		exitOnError(fmt.Errorf("verify mode only supports the %s format", formatMarkdown))
+	}
+	if output == stdoutOutput {
+		exitOnError(fmt.Errorf("verify mode requires an output file"))
+	}
+
+	fileName, err := outputPath(format, output)
+	checkError(err)
+
+	file, err := os.Open(fileName)
+	exitOnError(err)
+	defer file.Close()
+
+	documented, recordingRules, err := documentedMetrics(file)
+	exitOnError(err)
+
+	scraped, err := collector.ScrapeMetrics(opts)
+	exitOnError(err)
+
+	if report := compareExposed(documented, recordingRules, scraped); !report.empty() {
+		report.write(os.Stderr, fileName)
+		os.Exit(1)
+	}
+}